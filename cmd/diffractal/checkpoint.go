@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/spf13/pflag"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/checkpoint"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointOptions configure checkpointing of long Monte Carlo renders.
+var checkpointOptions struct {
+	Dir    string
+	Every  time.Duration
+	Resume bool
+}
+
+func addCheckpointFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&checkpointOptions.Dir, "checkpoint", "", "directory to periodically save worker state to, so the render can be resumed")
+	flags.DurationVar(&checkpointOptions.Every, "checkpoint-every", time.Minute, "how often to save the state of the render")
+	flags.BoolVar(&checkpointOptions.Resume, "resume", false, "continue the render saved in --checkpoint instead of starting over")
+}
+
+// resumeScene makes the scene saved with the checkpoint the scene file, unless one was given.
+// Call before loadScene so that flags still take precedence.
+func resumeScene() error {
+	if !checkpointOptions.Resume {
+		return nil
+	}
+	if checkpointOptions.Dir == "" {
+		return errors.New("--resume requires --checkpoint")
+	}
+
+	if shared.SceneFile == "" {
+		shared.SceneFile = filepath.Join(checkpointOptions.Dir, checkpoint.SceneFile)
+	}
+
+	return nil
+}
+
+// openCheckpoint opens the checkpoint store for sc, or returns nil if checkpointing is off.
+//
+// Where the output is written doesn't change the render, so it is left out of the
+// saved scene and may differ when resuming.
+func openCheckpoint(sc *scene.Scene) (*checkpoint.Store, error) {
+	if checkpointOptions.Dir == "" {
+		return nil, nil
+	}
+
+	saved := *sc
+	saved.Output = scene.Output{}
+
+	header, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint.Open(checkpointOptions.Dir, header, checkpointOptions.Resume)
+}
+
+// A checkpointer saves the state of a render at most every checkpointOptions.Every:
+// its Sum, along with how far each worker had got when it last flushed its Shard.
+type checkpointer struct {
+	store *checkpoint.Store
+	sum   *accum.Sum
+
+	// state returns the checkpoint to save given the encoded sum. It is called while
+	// no shard can flush, so it may read what workers record when they flush.
+	state func(sum []byte) any
+
+	mu        sync.Mutex
+	lastSaved time.Time
+}
+
+func newCheckpointer(store *checkpoint.Store, sum *accum.Sum, state func(sum []byte) any) *checkpointer {
+	return &checkpointer{store: store, sum: sum, state: state, lastSaved: time.Now()}
+}
+
+// due reports whether it is time to save.
+func (c *checkpointer) due() bool {
+	if c.store == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Since(c.lastSaved) >= checkpointOptions.Every
+}
+
+// flush flushes shard, calling record as Shard.Flush does, and then saves the
+// render if it is due. Workers call it whenever shard is Full or a save is due,
+// at points where their work can be resumed from.
+func (c *checkpointer) flush(shard *accum.Shard, record func()) error {
+	shard.Flush(record)
+
+	// Only one of the workers finding a save due makes it.
+	c.mu.Lock()
+	due := c.store != nil && time.Since(c.lastSaved) >= checkpointOptions.Every
+	if due {
+		c.lastSaved = time.Now()
+	}
+	c.mu.Unlock()
+
+	if !due {
+		return nil
+	}
+	return c.save()
+}
+
+// save saves the render.
+func (c *checkpointer) save() error {
+	if c.store == nil {
+		return nil
+	}
+
+	return c.sum.Snapshot(func(data []byte) error {
+		return c.store.Save(c.state(data))
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/escapetime"
+	"github.com/willbeason/tree-fractal/pkg/perturb"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math"
+	"math/rand"
+)
+
+// renderDeep renders a plane of the z^2 + c family by perturbation around a reference
+// orbit from the center of the view, for zooms too deep for complex128.
+//
+// The center is taken from sc.Viewport.Center, if set, at the precision the zoom needs.
+func renderDeep(ctx context.Context, sc *scene.Scene, frame viewport.Frame, family transforms.Family, coloring escapetime.Coloring) (*accum.Buffer, error) {
+	distance, isDistance := coloring.(escapetime.Distance)
+	if _, ok := coloring.(escapetime.Smooth); !ok && !isDistance {
+		return nil, fmt.Errorf("deep zooms only support smooth and distance coloring, not %q", sc.Coloring)
+	}
+
+	prec := perturb.Precision(frame.PixelHeight())
+	center := perturb.NewComplex(complex(sc.Viewport.CenterX, sc.Viewport.CenterY), prec)
+	if sc.Viewport.Center != "" {
+		var err error
+		center, err = perturb.ParseComplex(sc.Viewport.Center, prec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	maxIterations := sc.Sampling.MaxIterations
+
+	b, err := escapetime.ParseBailout(sc.Bailout, transforms.Julia2{})
+	if err != nil {
+		return nil, err
+	}
+	radial, ok := b.(escapetime.Radial)
+	if !ok {
+		return nil, fmt.Errorf("deep zooms need a radius or norm bailout, not %q", sc.Bailout)
+	}
+
+	bailout := radial.Radius()
+	if isDistance {
+		bailout = math.Min(bailout, escapetime.DistanceBailout)
+	}
+
+	var reference *perturb.Reference
+	switch paramParams.Plane {
+	case "parameter":
+		var offset complex128
+		switch f := family.(type) {
+		case transforms.Mandelbrot:
+			offset = f.C
+		case transforms.Multibrot:
+			if f.N != 2 {
+				return nil, errors.New("deep zooms need the multibrot family with n=2")
+			}
+		default:
+			return nil, fmt.Errorf("deep zooms need the mandelbrot or multibrot family, not %s", paramParams.Family)
+		}
+		reference = perturb.Mandelbrot(center.Add(offset), bailout, maxIterations)
+	case "dynamical":
+		if paramParams.Julia == nil {
+			return nil, errors.New("rendering the dynamical plane requires --julia")
+		}
+		f, ok := family.At(paramParams.Julia.Value()).(transforms.Julia2)
+		if !ok {
+			return nil, fmt.Errorf("deep zooms need maps of the form z^2 + c, which the %s family doesn't have", paramParams.Family)
+		}
+		reference = perturb.Julia(center, f.C, bailout, maxIterations)
+	default:
+		return nil, fmt.Errorf("unknown plane %q, want parameter or dynamical", paramParams.Plane)
+	}
+
+	subPixels := sc.Sampling.SubPixels
+	tolerance := sc.Sampling.PeriodTolerance
+	pixelSize := frame.PixelHeight()
+
+	counts := renderPixels(ctx, frame.Width(), frame.Height(), sc.Sampling.Workers, sc.Sampling.Seed, func(r *rand.Rand, x, y int) float64 {
+		b := 0.0
+
+		for s := 0; s < subPixels; s++ {
+			// Slightly jitter points.
+			offset := frame.Offset(float64(x)+r.Float64(), float64(y)+r.Float64())
+
+			orbit := reference.Escape(offset, bailout, maxIterations, tolerance)
+			switch {
+			case isDistance && orbit.Escaped:
+				b += distance.Estimate(orbit.Z, orbit.Derivative, pixelSize)
+			case isDistance:
+				b += 1.0
+			case orbit.Escaped:
+				b += escapetime.SmoothCount(transforms.Julia2{}, 0, orbit.N, orbit.Z, bailout)
+			}
+		}
+
+		return b
+	})
+
+	return counts, nil
+}
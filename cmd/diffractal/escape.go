@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/checkpoint"
+	"github.com/willbeason/tree-fractal/pkg/escapetime"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"image"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JuliaNParams are the parameters of a transforms.JuliaN.
+type JuliaNParams struct {
+	C scene.Complex `json:"c"`
+	N scene.Complex `json:"n"`
+}
+
+// EscapeParams alternates between applying the First and Second transforms.
+type EscapeParams struct {
+	First  JuliaNParams `json:"first"`
+	Second JuliaNParams `json:"second"`
+
+	// StartHeight is the vertical extent of the region orbits start in, which is
+	// centered vertically on the origin and extends right from -StartHeight/2 to the
+	// width of the image. Starting points are sampled on a grid with the same
+	// dimensions as the image.
+	StartHeight float64 `json:"startHeight"`
+
+	// Channels, if any, are drawn in their own colors and added together, as in the
+	// Nebulabrot. Orbits are then followed for as many iterations as the largest
+	// window, rather than Sampling.MaxIterations; --max-iterations scales the windows
+	// instead. If empty, escaping orbits are drawn in a single channel colored by the
+	// palette.
+	Channels []EscapeChannel `json:"channels,omitempty"`
+
+	// Orbits is which orbits are drawn: escaping (the Buddhabrot), trapped (orbits
+	// which never escape, the anti-Buddhabrot), or all. Empty means escaping.
+	Orbits string `json:"orbits,omitempty"`
+
+	// MinIterations, if positive, skips escaping orbits which take fewer iterations,
+	// so that channels draw bands of escape times.
+	MinIterations int `json:"minIterations,omitempty"`
+
+	// Periods, if set, only draws trapped orbits found to fall into cycles of these
+	// numbers of steps. Needs a positive Sampling.PeriodTolerance.
+	Periods []int `json:"periods,omitempty"`
+
+	// Weight is how points are weighted by their step index, in the syntax of weightUsage.
+	// Empty means ramp.
+	Weight string `json:"weight,omitempty"`
+
+	// Sampler is how starting points are chosen: grid, for Sampling.SubPixels jittered
+	// points on every pixel of the starting region, or metropolis, for Sampling.Samples
+	// steps of a Metropolis-Hastings chain favoring orbits seen in the view.
+	// Empty means grid.
+	Sampler string `json:"sampler,omitempty"`
+
+	// Mutation is the size of the small mutations of the metropolis sampler, relative
+	// to the view height.
+	Mutation float64 `json:"mutation,omitempty"`
+}
+
+// counts sets counts[c] to the number of points of an orbit of n points which channel c,
+// with an iteration window of windows[c], draws. Escaping orbits are drawn whole if they
+// escape within the window, and trapped orbits are cut off at the window.
+// Period is the period in steps the orbit was found to have, or zero.
+// Orbits must be set, as runEscape does.
+//
+// Returns the largest count.
+func (p EscapeParams) counts(n int, escaped bool, period int, windows []int, counts []int) int {
+	drawn := false
+	switch {
+	case escaped:
+		drawn = p.Orbits != "trapped" && n >= p.MinIterations
+	case len(p.Periods) > 0:
+		drawn = p.Orbits != "escaping" && slices.Contains(p.Periods, period)
+	default:
+		drawn = p.Orbits == "trapped" || p.Orbits == "all"
+	}
+
+	most := 0
+	for c, window := range windows {
+		counts[c] = 0
+		switch {
+		case !drawn:
+		case escaped && n < window:
+			counts[c] = n
+		case !escaped:
+			counts[c] = min(n, window)
+		}
+		most = max(most, counts[c])
+	}
+
+	return most
+}
+
+const weightUsage = "how points of orbits are weighted by their step index k: flat, ramp[:RATE] (min(RATE k, 1)), " +
+	"power:P ((k+1)^P), or fraction:P (((k+1)/n)^P for orbits of n points)"
+
+// A stepWeight weights point k of an orbit of n points.
+type stepWeight func(k, n int) float64
+
+// parseWeight parses a stepWeight in the syntax of weightUsage.
+func parseWeight(spec string) (stepWeight, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+
+	value := 0.1
+	if hasArg {
+		var err error
+		value, err = strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing argument %q of weight %q: %w", arg, name, err)
+		}
+	} else if name == "power" || name == "fraction" {
+		return nil, fmt.Errorf("weight %s needs an exponent", name)
+	}
+
+	switch name {
+	case "flat":
+		return func(int, int) float64 { return 1.0 }, nil
+	case "ramp":
+		return func(k, _ int) float64 { return math.Min(value*float64(k), 1.0) }, nil
+	case "power":
+		return func(k, _ int) float64 { return math.Pow(float64(k+1), value) }, nil
+	case "fraction":
+		return func(k, n int) float64 { return math.Pow(float64(k+1)/float64(n), value) }, nil
+	default:
+		return nil, fmt.Errorf("unknown weight %q, want %s", spec, weightUsage)
+	}
+}
+
+// parsePeriods parses comma-separated periods.
+func parsePeriods(spec string) ([]int, error) {
+	var periods []int
+	for _, field := range strings.Split(spec, ",") {
+		period, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("parsing period %q: %w", field, err)
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// An EscapeChannel draws the orbits which escape within Window iterations in Color.
+type EscapeChannel struct {
+	Window int         `json:"window"`
+	Color  palette.RGB `json:"color"`
+
+	// ToneMap is the tone curve of the channel, in the syntax of tonemap.Parse.
+	// Empty means the scene's tone map.
+	ToneMap string `json:"tonemap,omitempty"`
+}
+
+const nebulaUsage = "semicolon-separated channels WINDOW:COLOR[:TONEMAP] drawing orbits which escape within " +
+	"WINDOW iterations in COLOR, such as 100:#ff0000;30:#00ff00:log;10:#0000ff; none draws one channel with the palette"
+
+// parseNebula parses channels in the syntax of nebulaUsage.
+func parseNebula(spec string) ([]EscapeChannel, error) {
+	if spec == "none" {
+		return nil, nil
+	}
+
+	var channels []EscapeChannel
+	for _, part := range strings.Split(spec, ";") {
+		fields := strings.SplitN(strings.TrimSpace(part), ":", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("parsing channel %q: want WINDOW:COLOR[:TONEMAP]", part)
+		}
+
+		window, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing window of channel %q: %w", part, err)
+		}
+
+		color, err := palette.ParseHex(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("parsing color of channel %q: %w", part, err)
+		}
+
+		channel := EscapeChannel{Window: window, Color: color}
+		if len(fields) == 3 {
+			channel.ToneMap = fields[2]
+		}
+		channels = append(channels, channel)
+	}
+
+	return channels, nil
+}
+
+// scaleWindows scales the windows of channels in proportion so that the largest is
+// maxIterations, keeping each at least one iteration.
+func scaleWindows(channels []EscapeChannel, maxIterations int) error {
+	if maxIterations <= 0 {
+		return fmt.Errorf("got --max-iterations %d, want a positive number of iterations", maxIterations)
+	}
+
+	largest := 0
+	for c, channel := range channels {
+		if channel.Window <= 0 {
+			return fmt.Errorf("channel %d has window %d, want a positive number of iterations", c, channel.Window)
+		}
+		largest = max(largest, channel.Window)
+	}
+
+	for c := range channels {
+		scaled := math.Round(float64(channels[c].Window) * float64(maxIterations) / float64(largest))
+		channels[c].Window = max(1, int(scaled))
+	}
+
+	return nil
+}
+
+// escapeNebula and escapePeriods hold --nebula and --periods until it is known
+// whether the flags were set.
+var escapeNebula, escapePeriods string
+
+var escapeParams = EscapeParams{
+	First:       JuliaNParams{C: scene.NewComplex(complex(0.09, -0.575)), N: scene.Complex{5.0, 0.0}},
+	Second:      JuliaNParams{C: scene.NewComplex(complex(0.09, -0.575)), N: scene.Complex{6.0, 0.0}},
+	StartHeight: 8.0,
+	Weight:      "ramp:0.1",
+	Sampler:     "grid",
+	Mutation:    0.01,
+	Channels: []EscapeChannel{
+		{Window: 100, Color: palette.RGB{R: 1.0}},
+		{Window: 30, Color: palette.RGB{G: 1.0}},
+		{Window: 10, Color: palette.RGB{B: 1.0}},
+	},
+}
+
+var escapeScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "escape", Params: &escapeParams},
+	// The region of the plane orbits are drawn in.
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		CenterY:    -0.6,
+		ViewHeight: 1.2,
+	},
+	Sampling: scene.Sampling{
+		// 1e4 = 25 seconds
+		SubPixels:       1000,
+		MaxIterations:   100,
+		PeriodTolerance: 1e-10,
+		Splat:           "bilinear",
+		Samples:         10_000_000,
+		WarmUp:          1000,
+	},
+	Bailout: "radius:10",
+	ToneMap: "gamma:0.2,linear:1.25",
+	Palette: "escape",
+}
+
+func escapeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "escape",
+		Short: "Render the density of escaping orbits of alternating JuliaN transforms",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runEscape,
+	}
+
+	flags := cmd.Flags()
+	escapeScene.AddFlags(flags)
+	addCheckpointFlags(flags)
+	flags.Float64Var(&escapeScene.Sampling.PeriodTolerance, "period-tolerance", escapeScene.Sampling.PeriodTolerance, periodToleranceUsage)
+	flags.IntVar(&escapeScene.Sampling.SubPixels, "subpixels", escapeScene.Sampling.SubPixels, "orbits started per pixel of the starting grid")
+	flags.StringVar(&escapeScene.Sampling.Splat, "splat", escapeScene.Sampling.Splat, "how orbit points are spread over pixels: nearest, bilinear, or gaussian:SIGMA")
+	flags.Var(&escapeParams.First.C, "first-c", "additive constant C of the first JuliaN transform, as real,imaginary")
+	flags.Var(&escapeParams.First.N, "first-n", "exponent N of the first JuliaN transform, as real,imaginary")
+	flags.Var(&escapeParams.Second.C, "second-c", "additive constant C of the second JuliaN transform, as real,imaginary")
+	flags.Var(&escapeParams.Second.N, "second-n", "exponent N of the second JuliaN transform, as real,imaginary")
+	flags.Float64Var(&escapeParams.StartHeight, "start-height", escapeParams.StartHeight, "vertical extent of the region orbits start in")
+	flags.StringVar(&escapeNebula, "nebula", "", nebulaUsage)
+	flags.StringVar(&escapeParams.Orbits, "orbits", escapeParams.Orbits, "orbits to draw: escaping, trapped (never escaping, the anti-Buddhabrot), or all")
+	flags.IntVar(&escapeParams.MinIterations, "min-iterations", escapeParams.MinIterations, "skip escaping orbits shorter than this, making channel windows into bands")
+	flags.StringVar(&escapePeriods, "periods", "", "comma-separated periods, in steps, of the trapped orbits to draw; needs --period-tolerance")
+	flags.StringVar(&escapeParams.Weight, "weight", escapeParams.Weight, weightUsage)
+	flags.StringVar(&escapeScene.Bailout, "bailout", escapeScene.Bailout, escapetime.BailoutUsage)
+	flags.StringVar(&escapeParams.Sampler, "sampler", escapeParams.Sampler, "how starting points are chosen: grid, or metropolis to favor orbits seen in the view, as for zoomed renders")
+	flags.Int64Var(&escapeScene.Sampling.Samples, "samples", escapeScene.Sampling.Samples, "total steps of the metropolis sampler across all workers")
+	flags.IntVar(&escapeScene.Sampling.WarmUp, "warm-up", escapeScene.Sampling.WarmUp, "steps each metropolis chain takes before drawing")
+	flags.Float64Var(&escapeParams.Mutation, "mutation", escapeParams.Mutation, "size of small metropolis mutations relative to the view height")
+
+	return cmd
+}
+
+// escapeState is the checkpointed state of an escape render with the grid sampler.
+type escapeState struct {
+	// Rows are the next rows each worker starts orbits from.
+	Rows []int
+
+	// Frequencies is a Snapshot of the frequencies accumulated so far.
+	Frequencies []byte
+}
+
+func runEscape(cmd *cobra.Command, _ []string) error {
+	sc := &escapeScene
+	params := &escapeParams
+
+	err := resumeScene()
+	if err != nil {
+		return err
+	}
+
+	err = loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("nebula") {
+		params.Channels, err = parseNebula(escapeNebula)
+		if err != nil {
+			return err
+		}
+	}
+	if len(params.Channels) > 0 && cmd.Flags().Changed("max-iterations") {
+		err = scaleWindows(params.Channels, sc.Sampling.MaxIterations)
+		if err != nil {
+			return err
+		}
+	}
+	if cmd.Flags().Changed("periods") {
+		params.Periods, err = parsePeriods(escapePeriods)
+		if err != nil {
+			return err
+		}
+	}
+
+	if params.Orbits == "" {
+		params.Orbits = "escaping"
+	}
+	switch params.Orbits {
+	case "escaping", "trapped", "all":
+	default:
+		return fmt.Errorf("unknown orbits %q, want escaping, trapped, or all", params.Orbits)
+	}
+	if len(params.Periods) > 0 && sc.Sampling.PeriodTolerance <= 0.0 {
+		return errors.New("filtering orbits by period needs a positive --period-tolerance")
+	}
+
+	weight, err := parseWeight(params.Weight)
+	if err != nil {
+		return err
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	splatter, err := accum.ParseSplatter(sc.Sampling.Splat)
+	if err != nil {
+		return err
+	}
+
+	f := transforms.Alternate(
+		transforms.JuliaN{C: params.First.C.Value(), N: params.First.N.Value()},
+		transforms.JuliaN{C: params.Second.C.Value(), N: params.Second.N.Value()},
+	)
+
+	bailout, err := escapetime.ParseBailout(sc.Bailout, f)
+	if err != nil {
+		return err
+	}
+
+	frame, err := sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+	width, height := frame.Width(), frame.Height()
+
+	maxIterations := sc.Sampling.MaxIterations
+	tolerance := sc.Sampling.PeriodTolerance
+
+	// Each channel draws the orbits escaping within its window.
+	windows := []int{maxIterations}
+	if len(params.Channels) > 0 {
+		windows = make([]int, len(params.Channels))
+		maxIterations = 0
+		for c, channel := range params.Channels {
+			if channel.Window <= 0 {
+				return fmt.Errorf("channel %d has window %d, want a positive number of iterations", c, channel.Window)
+			}
+			windows[c] = channel.Window
+			maxIterations = max(maxIterations, channel.Window)
+		}
+	}
+
+	start := sc.Viewport
+	start.CenterY = 0.0
+	start.ViewHeight = params.StartHeight
+	start.Rotation = 0.0
+	// The region starts StartHeight/2 left of the origin, and is as wide as the image.
+	start.CenterX = 0.5 * (start.ViewWidth() - params.StartHeight)
+	startFrame, err := start.Frame()
+	if err != nil {
+		return fmt.Errorf("starting region: %w", err)
+	}
+
+	sum := accum.NewSum(width, height, len(windows))
+
+	render := &escapeRender{
+		params:        params,
+		f:             f,
+		frame:         frame,
+		splatter:      splatter,
+		bailout:       bailout,
+		weight:        weight,
+		windows:       windows,
+		maxIterations: maxIterations,
+		tolerance:     tolerance,
+	}
+
+	store, err := openCheckpoint(sc)
+	if err != nil {
+		return err
+	}
+
+	var frequencies *accum.Buffer
+	switch params.Sampler {
+	case "", "grid":
+		frequencies, err = sampleGrid(cmd.Context(), sc, render, startFrame, sum, store)
+	case "metropolis":
+		frequencies, err = sampleMetropolis(cmd.Context(), sc, render, startFrame, sum, store)
+	default:
+		return fmt.Errorf("unknown sampler %q, want grid or metropolis", params.Sampler)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = sc.WriteRaw(frequencies)
+	if err != nil {
+		return err
+	}
+
+	if len(params.Channels) > 0 {
+		img, err := mixChannels(frequencies, params.Channels, sc.ToneMap)
+		if err != nil {
+			return err
+		}
+		return sc.Output.WritePNG(img)
+	}
+
+	hits := frequencies.Channel(0)
+	toneMapper.Apply(hits)
+
+	img := palette.Render(pal, hits, width, height)
+
+	return sc.Output.WritePNG(img)
+}
+
+// sampleGrid accumulates into frequencies the orbits started from jittered points on
+// every pixel of a grid over startFrame, Sampling.SubPixels per pixel, and returns them.
+func sampleGrid(ctx context.Context, sc *scene.Scene, render *escapeRender, startFrame viewport.Frame, frequencies *accum.Sum, store *checkpoint.Store) (*accum.Buffer, error) {
+	width, height := frequencies.Width, frequencies.Height
+	subPixels := sc.Sampling.SubPixels
+	parallel := sc.Sampling.Workers
+
+	// Each worker takes every parallel-th row, so for a given seed and number of workers
+	// every row is sampled the same way whichever worker takes it.
+	state := escapeState{Rows: make([]int, parallel)}
+	for i := range state.Rows {
+		state.Rows[i] = i
+	}
+
+	resumed, err := store.Load(&state)
+	if err != nil {
+		return nil, err
+	}
+	if resumed {
+		if len(state.Rows) != parallel {
+			return nil, fmt.Errorf("checkpoint has %d workers, want %d", len(state.Rows), parallel)
+		}
+		err = frequencies.Restore(state.Frequencies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows := startProgress("rows", int64(height))
+	for i, row := range state.Rows {
+		rows.Skip(int64((row - i) / parallel))
+	}
+
+	saver := newCheckpointer(store, frequencies, func(data []byte) any {
+		return &escapeState{Rows: state.Rows, Frequencies: data}
+	})
+
+	errs := make([]error, parallel)
+
+	ywg := sync.WaitGroup{}
+	ywg.Add(parallel)
+	for i := 0; i < parallel; i++ {
+		go func() {
+			defer ywg.Done()
+
+			path := make([]complex128, render.maxIterations)
+			counts := make([]int, len(render.windows))
+			shard := frequencies.Shard()
+
+			y := state.Rows[i]
+			for ; y < height && ctx.Err() == nil; y += parallel {
+				r := rng.New(sc.Sampling.Seed, y)
+				for x := 0; x < width; x++ {
+					for s := 0; s < subPixels; s++ {
+						// Slightly jitter points.
+						sx, sy := startFrame.FromPixel(float64(x)+r.Float64(), float64(y)+r.Float64())
+
+						most, n := render.trace(complex(sy, sx), path, counts)
+						render.draw(shard, path, most, n, counts, 1.0)
+					}
+				}
+
+				rows.Add(1)
+
+				if shard.Full() || saver.due() {
+					errs[i] = saver.flush(shard, func() { state.Rows[i] = y + parallel })
+					if errs[i] != nil {
+						return
+					}
+				}
+			}
+
+			// Record where an interrupted worker stopped so that it can be resumed.
+			shard.Flush(func() { state.Rows[i] = y })
+		}()
+	}
+
+	ywg.Wait()
+	stopProgress(ctx, rows)
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() != nil {
+		err = saver.save()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return frequencies.Buffer(), nil
+}
+
+// escapeRender is what the orbits of an escape render are followed and drawn with.
+type escapeRender struct {
+	params   *EscapeParams
+	f        transforms.ComplexMap
+	frame    viewport.Frame
+	splatter accum.Splatter
+	bailout  escapetime.Bailout
+	weight   stepWeight
+
+	// windows are the iteration windows of each channel.
+	windows []int
+
+	maxIterations int
+	tolerance     float64
+}
+
+// trace follows the orbit from z into path and sets counts to the number of its points
+// each channel draws. Returns the largest count and the length of the orbit.
+func (e *escapeRender) trace(z complex128, path []complex128, counts []int) (int, int) {
+	orbit := escapetime.Trace(e.f, 0, z, e.bailout, e.maxIterations, e.tolerance, func(n int, z complex128) bool {
+		path[n-1] = z
+		return true
+	})
+
+	// Iterate counts orbits which only escape on their last step as not escaping, but
+	// they are still drawn as escaping orbits.
+	escaped := orbit.Escaped
+	if !escaped && orbit.Period == 0 && orbit.N > 0 {
+		last := z
+		if orbit.N > 1 {
+			last = path[orbit.N-2]
+		}
+		escaped = e.bailout.Escaped(orbit.Z, orbit.Z-last)
+	}
+
+	iterations := orbit.N
+	if orbit.Period > 0 && e.params.Orbits != "escaping" {
+		// The rest of a periodic orbit goes around its cycle.
+		for ; iterations < e.maxIterations; iterations++ {
+			path[iterations] = path[iterations-orbit.Period]
+		}
+	}
+
+	return e.params.counts(iterations, escaped, orbit.Period, e.windows, counts), iterations
+}
+
+// draw splats the first counts[c] points of the orbit of n points in path into
+// channel c of shard, weighted by scale. Most is the largest count.
+func (e *escapeRender) draw(shard *accum.Shard, path []complex128, most, n int, counts []int, scale float64) {
+	for k, z := range path[:most] {
+		px, py := e.frame.ToPixelZ(z)
+		baseBrightness := e.weight(k, n) * scale
+		for c, count := range counts {
+			if k < count {
+				e.splatter.Splat(shard, px, py, c, baseBrightness)
+			}
+		}
+	}
+}
+
+// visible returns how many of the points traced into path that the channels draw land
+// in the view, counting points drawn by several channels once for each. Points within
+// a pixel of the view count, as splats reach that far into it.
+func (e *escapeRender) visible(path []complex128, counts []int) int {
+	width, height := float64(e.frame.Width()), float64(e.frame.Height())
+
+	result := 0
+	for _, count := range counts {
+		for _, z := range path[:count] {
+			px, py := e.frame.ToPixelZ(z)
+			if px > -1.0 && py > -1.0 && px < width+1.0 && py < height+1.0 {
+				result++
+			}
+		}
+	}
+
+	return result
+}
+
+// mixChannels tone maps each channel of buffer with its own tone curve, or toneMap
+// if it has none, and adds them together in their colors.
+func mixChannels(buffer *accum.Buffer, channels []EscapeChannel, toneMap string) (*image.RGBA64, error) {
+	colors := make([]palette.RGB, len(channels))
+	values := make([][]float64, len(channels))
+
+	for c, channel := range channels {
+		spec := channel.ToneMap
+		if spec == "" {
+			spec = toneMap
+		}
+
+		toneMapper, err := tonemap.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("tone map of channel %d: %w", c, err)
+		}
+
+		colors[c] = channel.Color
+		values[c] = buffer.Channel(c)
+		toneMapper.Apply(values[c])
+	}
+
+	return palette.Mix(colors, values, buffer.Width, buffer.Height), nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseWeight(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		k, n    int
+		want    float64
+		wantErr bool
+	}{
+		{spec: "flat", k: 7, n: 10, want: 1.0},
+		{spec: "ramp", k: 5, n: 100, want: 0.5},
+		{spec: "ramp", k: 50, n: 100, want: 1.0},
+		{spec: "ramp:0.25", k: 2, n: 100, want: 0.5},
+		{spec: "power:2", k: 2, n: 10, want: 9.0},
+		{spec: "fraction:1", k: 4, n: 10, want: 0.5},
+		{spec: "power", wantErr: true},
+		{spec: "fraction", wantErr: true},
+		{spec: "ramp:fast", wantErr: true},
+		{spec: "heavy", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.spec, func(t *testing.T) {
+			weight, err := parseWeight(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("got nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := weight(tc.k, tc.n); math.Abs(got-tc.want) > 1e-12 {
+				t.Errorf("weight(%d, %d) = %v, want %v", tc.k, tc.n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParsePeriods(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		want    []int
+		wantErr bool
+	}{
+		{spec: "2", want: []int{2}},
+		{spec: "1, 3,4", want: []int{1, 3, 4}},
+		{spec: "", wantErr: true},
+		{spec: "2,", wantErr: true},
+		{spec: "two", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		got, err := parsePeriods(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parsePeriods(%q) = %v, want error", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePeriods(%q): %v", tc.spec, err)
+			continue
+		}
+
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("parsePeriods(%q) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestEscapeParams_counts(t *testing.T) {
+	windows := []int{10, 100}
+
+	tcs := []struct {
+		name    string
+		params  EscapeParams
+		n       int
+		escaped bool
+		period  int
+		want    []int
+	}{
+		{name: "escaping drawn", params: EscapeParams{Orbits: "escaping"}, n: 50, escaped: true, want: []int{0, 50}},
+		{name: "escaping skips trapped", params: EscapeParams{Orbits: "escaping"}, n: 100, want: []int{0, 0}},
+		{name: "trapped cut off", params: EscapeParams{Orbits: "trapped"}, n: 100, want: []int{10, 100}},
+		{name: "trapped skips escaping", params: EscapeParams{Orbits: "trapped"}, n: 50, escaped: true, want: []int{0, 0}},
+		{name: "below min iterations", params: EscapeParams{Orbits: "all", MinIterations: 60}, n: 50, escaped: true, want: []int{0, 0}},
+		{name: "period matches", params: EscapeParams{Orbits: "trapped", Periods: []int{2}}, n: 100, period: 2, want: []int{10, 100}},
+		{name: "period differs", params: EscapeParams{Orbits: "trapped", Periods: []int{2}}, n: 100, period: 4, want: []int{0, 0}},
+		{name: "periods of escaping orbits", params: EscapeParams{Orbits: "escaping", Periods: []int{2}}, n: 100, period: 2, want: []int{0, 0}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := make([]int, len(windows))
+			most := tc.params.counts(tc.n, tc.escaped, tc.period, windows, got)
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got counts %v, want %v", got, tc.want)
+			}
+			if most != slices.Max(tc.want) {
+				t.Errorf("got largest count %d, want %d", most, slices.Max(tc.want))
+			}
+		})
+	}
+}
+
+func TestParseNebula(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		want    []EscapeChannel
+		wantErr bool
+	}{
+		{spec: "none"},
+		{
+			spec: "100:#ff0000",
+			want: []EscapeChannel{{Window: 100, Color: palette.RGB{R: 1}}},
+		},
+		{
+			spec: "100:#ff0000; 10:#0000ff:log",
+			want: []EscapeChannel{
+				{Window: 100, Color: palette.RGB{R: 1}},
+				{Window: 10, Color: palette.RGB{B: 1}, ToneMap: "log"},
+			},
+		},
+		{spec: "100", wantErr: true},
+		{spec: "x:#ff0000", wantErr: true},
+		{spec: "100:red", wantErr: true},
+		{spec: "100:#ff0000;", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		got, err := parseNebula(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseNebula(%q) = %v, want error", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseNebula(%q): %v", tc.spec, err)
+			continue
+		}
+
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("parseNebula(%q) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestScaleWindows(t *testing.T) {
+	tcs := []struct {
+		name          string
+		windows       []int
+		maxIterations int
+		want          []int
+		wantErr       bool
+	}{
+		{name: "unchanged", windows: []int{100, 30, 10}, maxIterations: 100, want: []int{100, 30, 10}},
+		{name: "scaled up", windows: []int{100, 30, 10}, maxIterations: 1000, want: []int{1000, 300, 100}},
+		{name: "scaled down", windows: []int{100, 30, 10}, maxIterations: 50, want: []int{50, 15, 5}},
+		{name: "rounded", windows: []int{30, 10}, maxIterations: 20, want: []int{20, 7}},
+		{name: "at least one", windows: []int{1000, 1}, maxIterations: 10, want: []int{10, 1}},
+		{name: "largest anywhere", windows: []int{10, 40}, maxIterations: 80, want: []int{20, 80}},
+		{name: "zero iterations", windows: []int{100}, maxIterations: 0, wantErr: true},
+		{name: "zero window", windows: []int{100, 0}, maxIterations: 100, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			channels := make([]EscapeChannel, len(tc.windows))
+			for c, window := range tc.windows {
+				channels[c].Window = window
+			}
+
+			err := scaleWindows(channels, tc.maxIterations)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("got nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]int, len(channels))
+			for c, channel := range channels {
+				got[c] = channel.Window
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got windows %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
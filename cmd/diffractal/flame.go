@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/flame"
+	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/progress"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"image"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FlameTransform is one map of a fractal flame, with the colour it pulls points toward.
+type FlameTransform struct {
+	IFSTransform
+
+	// Color is the position in the palette of the colour the transform blends points toward.
+	Color float64 `json:"color"`
+
+	// ColorSpeed, in [0, 1], is how far each application of the transform moves the
+	// colour of a point toward Color. Zero leaves colours unchanged.
+	ColorSpeed float64 `json:"colorSpeed"`
+}
+
+// blend returns colour c moved toward the colour of t.
+func (t FlameTransform) blend(c float64) float64 {
+	return c + (t.Color-c)*t.ColorSpeed
+}
+
+// FlameParams describe a fractal flame and how to tone map it.
+type FlameParams struct {
+	Transforms []FlameTransform `json:"transforms"`
+
+	// Final, if set, is applied to every point before it is plotted, without
+	// changing the orbit the point is on.
+	Final *FlameTransform `json:"final,omitempty"`
+
+	// AutoFrame is whether to fit the view to the flame, keeping only the
+	// rotation and aspect of the viewport.
+	AutoFrame bool `json:"autoFrame"`
+
+	// Brightness, Gamma, and Vibrancy are those of flame.ToneMap.
+	Brightness float64 `json:"brightness"`
+	Gamma      float64 `json:"gamma"`
+	Vibrancy   float64 `json:"vibrancy"`
+
+	Density flame.Estimator `json:"density"`
+}
+
+// Transform returns the ProbabilisticTransform choosing among the maps of p.
+func (p FlameParams) Transform() (transforms.ProbabilisticTransform, error) {
+	ifs := IFSParams{}
+	for _, t := range p.Transforms {
+		ifs.Transforms = append(ifs.Transforms, t.IFSTransform)
+	}
+
+	return ifs.Transform()
+}
+
+// toneMap returns the flame.ToneMap p describes.
+func (p FlameParams) toneMap() (flame.ToneMap, error) {
+	if !(p.Gamma > 0.0) {
+		return flame.ToneMap{}, fmt.Errorf("gamma must be positive, got %v", p.Gamma)
+	}
+	if !(p.Vibrancy >= 0.0 && p.Vibrancy <= 1.0) {
+		return flame.ToneMap{}, fmt.Errorf("vibrancy must be in [0, 1], got %v", p.Vibrancy)
+	}
+
+	return flame.ToneMap{Brightness: p.Brightness, Gamma: p.Gamma, Vibrancy: p.Vibrancy}, nil
+}
+
+// render estimates the density of b, into which samples points of the flame were
+// plotted, and tone maps it.
+func (p FlameParams) render(b *accum.Buffer, samples int64) (*image.RGBA64, error) {
+	toneMap, err := p.toneMap()
+	if err != nil {
+		return nil, err
+	}
+
+	samplesPerPixel := float64(samples) / float64(b.Width*b.Height)
+	return toneMap.Render(p.Density.Apply(b), samplesPerPixel), nil
+}
+
+// flamePresets are example flames by name.
+var flamePresets = map[string]FlameParams{
+	"swirl": {
+		Transforms: []FlameTransform{
+			{IFSTransform: ifsPresets["swirl"][0], Color: 0.0, ColorSpeed: 0.5},
+			{IFSTransform: ifsPresets["swirl"][1], Color: 0.5, ColorSpeed: 0.5},
+			{IFSTransform: ifsPresets["swirl"][2], Color: 1.0, ColorSpeed: 0.5},
+		},
+	},
+	"spherical": {
+		Transforms: []FlameTransform{
+			{
+				IFSTransform: IFSTransform{Affine: transforms.Rotation(0.3, 0.9, 0.2, 0.0), Variations: "spherical:0.7,linear:0.3", Weight: 2.0},
+				Color:        0.1, ColorSpeed: 0.5,
+			},
+			{
+				IFSTransform: IFSTransform{Affine: transforms.Rotation(2.4, 0.6, -0.5, 0.3), Variations: "julia", Weight: 1.0},
+				Color:        0.6, ColorSpeed: 0.5,
+			},
+			{
+				IFSTransform: IFSTransform{
+					Affine:     transforms.Rotation(-1.2, 0.5, 0.4, -0.6),
+					Variations: "swirl:0.6,bubble:0.4",
+					Post:       &transforms.Affine{A: 1.2, E: 1.2},
+					Weight:     1.0,
+				},
+				Color: 0.9, ColorSpeed: 0.7,
+			},
+		},
+		Final: &FlameTransform{
+			IFSTransform: IFSTransform{Affine: transforms.IdentityAffine, Variations: "eyefish:0.8,linear:0.2"},
+		},
+	},
+}
+
+// flamePresetNames returns the names of flamePresets in alphabetical order.
+func flamePresetNames() []string {
+	var names []string
+	for name := range flamePresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// flamePreset holds --preset until it is known whether the flag was set.
+var flamePreset string
+
+var flameParams = FlameParams{
+	Transforms: flamePresets["swirl"].Transforms,
+	AutoFrame:  true,
+	Brightness: 4.0,
+	Gamma:      4.0,
+	Vibrancy:   1.0,
+	Density: flame.Estimator{
+		MaxRadius: 9.0,
+		Curve:     0.4,
+	},
+}
+
+var flameScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "flame", Params: &flameParams},
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		ViewHeight: 2.0,
+	},
+	Sampling: scene.Sampling{
+		Samples: 1e8,
+		WarmUp:  20,
+		Splat:   "bilinear",
+	},
+	Palette: "flame",
+}
+
+func flameCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flame",
+		Short: "Render a fractal flame, coloured by the transforms its points pass through",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runFlame,
+	}
+
+	flags := cmd.Flags()
+	flameScene.Viewport.AddFlags(flags)
+	flags.StringVar(&flameScene.Palette, "palette", flameScene.Palette, "colours of the transforms' colour coordinates: "+palette.Usage)
+	flags.Int64Var(&flameScene.Sampling.Samples, "samples", flameScene.Sampling.Samples, "total points plotted across all workers")
+	flags.IntVar(&flameScene.Sampling.WarmUp, "warm-up", flameScene.Sampling.WarmUp, "steps each worker takes to reach the attractor before plotting")
+	flags.StringVar(&flameScene.Sampling.Splat, "splat", flameScene.Sampling.Splat, "how points are spread over pixels: nearest, bilinear, or gaussian:SIGMA")
+	flags.StringVar(&flamePreset, "preset", "", "flame to render instead of the scene's transforms: "+strings.Join(flamePresetNames(), ", "))
+	flags.BoolVar(&flameParams.AutoFrame, "auto-frame", flameParams.AutoFrame, "fit the view to the flame; false to frame it with --center-x, --center-y, and --view-height")
+	flags.Float64Var(&flameParams.Brightness, "brightness", flameParams.Brightness, "scale of the log density")
+	flags.Float64Var(&flameParams.Gamma, "gamma", flameParams.Gamma, "gamma correction, which brightens faint parts of the flame")
+	flags.Float64Var(&flameParams.Vibrancy, "vibrancy", flameParams.Vibrancy, "how much gamma correction keeps colours saturated, in [0, 1]")
+	flags.Float64Var(&flameParams.Density.MaxRadius, "de-max-radius", flameParams.Density.MaxRadius, "radius in pixels of the density estimation blur of pixels with one sample; 0 disables it")
+	flags.Float64Var(&flameParams.Density.MinRadius, "de-min-radius", flameParams.Density.MinRadius, "smallest radius in pixels of the density estimation blur")
+	flags.Float64Var(&flameParams.Density.Curve, "de-curve", flameParams.Density.Curve, "how fast the density estimation blur narrows as pixels get denser")
+
+	return cmd
+}
+
+func runFlame(cmd *cobra.Command, _ []string) error {
+	sc := &flameScene
+	params := &flameParams
+
+	err := loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("preset") {
+		preset, ok := flamePresets[flamePreset]
+		if !ok {
+			return fmt.Errorf("unknown preset %q, want one of %s", flamePreset, strings.Join(flamePresetNames(), ", "))
+		}
+		params.Transforms, params.Final = preset.Transforms, preset.Final
+	}
+
+	// Check the tone map before rendering rather than after.
+	_, err = params.toneMap()
+	if err != nil {
+		return err
+	}
+
+	pt, err := params.Transform()
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	splatter, err := accum.ParseSplatter(sc.Sampling.Splat)
+	if err != nil {
+		return err
+	}
+
+	render := &flameRender{
+		params:   params,
+		pt:       pt,
+		splatter: splatter,
+		warmUp:   sc.Sampling.WarmUp,
+	}
+
+	if params.Final != nil {
+		render.final, err = params.Final.Transform()
+		if err != nil {
+			return fmt.Errorf("final transform: %w", err)
+		}
+	}
+
+	for i := range render.colors {
+		c := pal.At(float64(i) / float64(len(render.colors)-1))
+		render.colors[i] = palette.RGB{R: float64(c.R) / 0xffff, G: float64(c.G) / 0xffff, B: float64(c.B) / 0xffff}
+	}
+
+	if params.AutoFrame {
+		var final transforms.Transform
+		if params.Final != nil {
+			final = render.final
+		}
+
+		err = frameAttractor(&sc.Viewport, pt, final, rng.New(sc.Sampling.Seed), sc.Sampling.WarmUp)
+		if err != nil {
+			return err
+		}
+	}
+
+	render.frame, err = sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+	width, height := render.frame.Width(), render.frame.Height()
+
+	sum := accum.NewSum(width, height, flame.Channels)
+
+	ctx := cmd.Context()
+	done := startProgress("points", sc.Sampling.Samples)
+
+	nWorkers := sc.Sampling.Workers
+
+	wg := sync.WaitGroup{}
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			defer wg.Done()
+
+			// Split the samples as evenly as possible.
+			n := sc.Sampling.Samples / int64(nWorkers)
+			if int64(i) < sc.Sampling.Samples%int64(nWorkers) {
+				n++
+			}
+
+			shard := sum.Shard()
+			render.play(ctx, rng.New(sc.Sampling.Seed, i), n, shard, done)
+			shard.Flush(nil)
+		}()
+	}
+
+	wg.Wait()
+	stopProgress(ctx, done)
+	buffer := sum.Buffer()
+
+	err = sc.WriteRaw(buffer)
+	if err != nil {
+		return err
+	}
+
+	img, err := params.render(buffer, sc.Sampling.Samples)
+	if err != nil {
+		return err
+	}
+
+	return sc.Output.WritePNG(img)
+}
+
+// flameRender is what the chaos game of a flame is played and drawn with.
+type flameRender struct {
+	params *FlameParams
+	pt     transforms.ProbabilisticTransform
+
+	// final is the final transform, if params.Final is set.
+	final transforms.Blend
+
+	frame    viewport.Frame
+	splatter accum.Splatter
+	warmUp   int
+
+	// colors is the palette sampled at evenly spaced colour coordinates.
+	colors [256]palette.RGB
+}
+
+// play plots n points of the flame, after warmUp steps to reach its attractor,
+// or fewer if ctx is cancelled. Orbits which overflow start again.
+func (f *flameRender) play(ctx context.Context, r *rand.Rand, n int64, out *accum.Shard, done *progress.Reporter) {
+	p, c := f.burnIn(r)
+
+	for i := int64(0); i < n; i++ {
+		if i%walkChunk == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			done.Add(min(walkChunk, n-i))
+			if out.Full() {
+				out.Flush(nil)
+			}
+		}
+
+		p, c = f.step(p, c, r)
+		if !finite(p) {
+			p, c = f.burnIn(r)
+			continue
+		}
+
+		q, qc := p, c
+		if f.params.Final != nil {
+			q, qc = f.final.Next(p, r), f.params.Final.blend(c)
+			if !finite(q) {
+				continue
+			}
+		}
+
+		rgb := f.colors[int(math.Max(0.0, math.Min(qc, 1.0))*float64(len(f.colors)-1))]
+
+		// Points outside the view are still part of the orbit, they just aren't drawn.
+		px, py := f.frame.ToPixel(q.X, q.Y)
+		f.splatter.Splat(out, px, py, flame.Red, rgb.R)
+		f.splatter.Splat(out, px, py, flame.Green, rgb.G)
+		f.splatter.Splat(out, px, py, flame.Blue, rgb.B)
+		f.splatter.Splat(out, px, py, flame.Count, 1.0)
+	}
+}
+
+// step applies a transform chosen at random to the point p of colour c.
+func (f *flameRender) step(p geometry.XY, c float64, r *rand.Rand) (geometry.XY, float64) {
+	i := f.pt.Choose(r)
+	if i < 0 {
+		return f.pt.First(), c
+	}
+
+	return f.pt.Transforms[i].Next(p, r), f.params.Transforms[i].blend(c)
+}
+
+// burnIn returns the point and colour of an orbit warmUp steps after its start,
+// from a random colour.
+func (f *flameRender) burnIn(r *rand.Rand) (geometry.XY, float64) {
+	p, c := f.pt.First(), r.Float64()
+	for i := 0; i < f.warmUp; i++ {
+		p, c = f.step(p, c, r)
+	}
+	return p, c
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/progress"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// IFSTransform is one map of an iterated function system.
+type IFSTransform struct {
+	Affine transforms.Affine `json:"affine"`
+
+	// Variations are blended after the affine map, in the syntax of
+	// transforms.ParseVariations. Empty means the affine map alone.
+	Variations string `json:"variations,omitempty"`
+
+	// Post, if set, is an affine map applied after the variations.
+	Post *transforms.Affine `json:"post,omitempty"`
+
+	// Weight is the relative probability of choosing the transform. Zero means the
+	// factor the affine map scales areas by, which covers affine attractors evenly.
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// Transform returns the map t describes.
+func (t IFSTransform) Transform() (transforms.Blend, error) {
+	result := transforms.Blend{Affine: t.Affine, Post: t.Post}
+	if t.Variations == "" {
+		return result, nil
+	}
+
+	var err error
+	result.Variations, err = transforms.ParseVariations(t.Variations)
+	return result, err
+}
+
+// IFSParams are the maps of an iterated function system and how to frame its attractor.
+type IFSParams struct {
+	Transforms []IFSTransform `json:"transforms"`
+
+	// AutoFrame is whether to fit the view to the attractor, keeping only the
+	// rotation and aspect of the viewport.
+	AutoFrame bool `json:"autoFrame"`
+}
+
+// Transform returns the ProbabilisticTransform choosing among the maps of p.
+func (p IFSParams) Transform() (transforms.ProbabilisticTransform, error) {
+	if len(p.Transforms) == 0 {
+		return transforms.ProbabilisticTransform{}, errors.New("an iterated function system needs at least one transform")
+	}
+
+	ts := make([]transforms.Transform, len(p.Transforms))
+	weights := make([]float64, len(p.Transforms))
+	for i, t := range p.Transforms {
+		blend, err := t.Transform()
+		if err != nil {
+			return transforms.ProbabilisticTransform{}, fmt.Errorf("transform %d: %w", i, err)
+		}
+		ts[i] = blend
+
+		weights[i] = t.Weight
+		if weights[i] == 0.0 {
+			weights[i] = math.Abs(t.Affine.Determinant())
+		}
+	}
+
+	return transforms.NewProbabilisticTransform(transforms.StartPoint{}, ts, weights)
+}
+
+// ifsPresets are well-known iterated function systems by name.
+var ifsPresets = map[string][]IFSTransform{
+	"sierpinski": {
+		{Affine: transforms.Affine{A: 0.5, E: 0.5}},
+		{Affine: transforms.Affine{A: 0.5, C: 0.5, E: 0.5}},
+		{Affine: transforms.Affine{A: 0.5, C: 0.25, E: 0.5, F: math.Sqrt(3.0) / 4.0}},
+	},
+	"fern": {
+		{Affine: transforms.Affine{E: 0.16}, Weight: 0.01},
+		{Affine: transforms.Affine{A: 0.85, B: 0.04, D: -0.04, E: 0.85, F: 1.6}, Weight: 0.85},
+		{Affine: transforms.Affine{A: 0.2, B: -0.26, D: 0.23, E: 0.22, F: 1.6}, Weight: 0.07},
+		{Affine: transforms.Affine{A: -0.15, B: 0.28, D: 0.26, E: 0.24, F: 0.44}, Weight: 0.07},
+	},
+	"dragon": {
+		{Affine: transforms.Affine{A: 0.5, B: -0.5, D: 0.5, E: 0.5}},
+		{Affine: transforms.Affine{A: -0.5, B: -0.5, C: 1.0, D: 0.5, E: -0.5}},
+	},
+	"swirl": {
+		{Affine: transforms.Affine{A: 0.5, E: 0.5}, Variations: "linear:0.5,swirl:0.5", Weight: 1.0},
+		{Affine: transforms.Affine{A: 0.5, C: 0.5, E: 0.5}, Variations: "spherical:0.3,linear:0.7", Weight: 1.0},
+		{Affine: transforms.Affine{A: 0.5, C: 0.25, E: 0.5, F: 0.43}, Variations: "julia", Weight: 1.0},
+	},
+}
+
+// ifsPresetNames returns the names of ifsPresets in alphabetical order.
+func ifsPresetNames() []string {
+	var names []string
+	for name := range ifsPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ifsPreset holds --preset until it is known whether the flag was set.
+var ifsPreset string
+
+var ifsParams = IFSParams{
+	Transforms: ifsPresets["fern"],
+	AutoFrame:  true,
+}
+
+var ifsScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "ifs", Params: &ifsParams},
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		ViewHeight: 2.0,
+	},
+	Sampling: scene.Sampling{
+		Samples: 1e8,
+		WarmUp:  20,
+		Splat:   "bilinear",
+	},
+	ToneMap: "log",
+	Palette: "inferno",
+}
+
+// ifsFrameSamples is the number of points of the attractor used to frame it.
+const ifsFrameSamples = 100000
+
+// ifsFrameOutliers is the fraction of points on each side of the attractor that
+// framing ignores, so that the rare points thrown far out by nonlinear
+// variations don't shrink the rest of it to a speck.
+const ifsFrameOutliers = 0.001
+
+// ifsFrameMargin is the fraction of the framed attractor's extent left empty on each side.
+const ifsFrameMargin = 0.05
+
+func ifsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ifs",
+		Short: "Render the attractor of an iterated function system by the chaos game",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runIFS,
+	}
+
+	flags := cmd.Flags()
+	ifsScene.AddFlags(flags)
+	flags.Int64Var(&ifsScene.Sampling.Samples, "samples", ifsScene.Sampling.Samples, "total points plotted across all workers")
+	flags.IntVar(&ifsScene.Sampling.WarmUp, "warm-up", ifsScene.Sampling.WarmUp, "steps each worker takes to reach the attractor before plotting")
+	flags.StringVar(&ifsScene.Sampling.Splat, "splat", ifsScene.Sampling.Splat, "how points are spread over pixels: nearest, bilinear, or gaussian:SIGMA")
+	flags.StringVar(&ifsPreset, "preset", "", "iterated function system to render instead of the scene's transforms: "+strings.Join(ifsPresetNames(), ", "))
+	flags.BoolVar(&ifsParams.AutoFrame, "auto-frame", ifsParams.AutoFrame, "fit the view to the attractor; false to frame it with --center-x, --center-y, and --view-height")
+
+	return cmd
+}
+
+func runIFS(cmd *cobra.Command, _ []string) error {
+	sc := &ifsScene
+	params := &ifsParams
+
+	err := loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("preset") {
+		preset, ok := ifsPresets[ifsPreset]
+		if !ok {
+			return fmt.Errorf("unknown preset %q, want one of %s", ifsPreset, strings.Join(ifsPresetNames(), ", "))
+		}
+		params.Transforms = preset
+	}
+
+	pt, err := params.Transform()
+	if err != nil {
+		return err
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	splatter, err := accum.ParseSplatter(sc.Sampling.Splat)
+	if err != nil {
+		return err
+	}
+
+	if params.AutoFrame {
+		err = frameAttractor(&sc.Viewport, pt, nil, rng.New(sc.Sampling.Seed), sc.Sampling.WarmUp)
+		if err != nil {
+			return err
+		}
+	}
+
+	frame, err := sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+	width, height := frame.Width(), frame.Height()
+
+	sum := accum.NewSum(width, height, 1)
+
+	ctx := cmd.Context()
+	done := startProgress("points", sc.Sampling.Samples)
+
+	nWorkers := sc.Sampling.Workers
+
+	wg := sync.WaitGroup{}
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			defer wg.Done()
+
+			// Split the samples as evenly as possible.
+			n := sc.Sampling.Samples / int64(nWorkers)
+			if int64(i) < sc.Sampling.Samples%int64(nWorkers) {
+				n++
+			}
+
+			shard := sum.Shard()
+			chaosGame(ctx, pt, frame, splatter, rng.New(sc.Sampling.Seed, i), sc.Sampling.WarmUp, n, shard, done)
+			shard.Flush(nil)
+		}()
+	}
+
+	wg.Wait()
+	stopProgress(ctx, done)
+	buffer := sum.Buffer()
+
+	err = sc.WriteRaw(buffer)
+	if err != nil {
+		return err
+	}
+
+	counts := buffer.Channel(0)
+	toneMapper.Apply(counts)
+
+	img := palette.Render(pal, counts, width, height)
+
+	return sc.Output.WritePNG(img)
+}
+
+// chaosGame plots n points of the orbit of pt, after warmUp steps to reach its
+// attractor, or fewer if ctx is cancelled. Orbits which overflow start again.
+func chaosGame(ctx context.Context, pt transforms.ProbabilisticTransform, frame viewport.Frame, splatter accum.Splatter, r *rand.Rand, warmUp int, n int64, out *accum.Shard, done *progress.Reporter) {
+	p := burnIn(pt, r, warmUp)
+
+	for i := int64(0); i < n; i++ {
+		if i%walkChunk == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			done.Add(min(walkChunk, n-i))
+			if out.Full() {
+				out.Flush(nil)
+			}
+		}
+
+		p = pt.Next(p, r)
+		if !finite(p) {
+			p = burnIn(pt, r, warmUp)
+			continue
+		}
+
+		// Points outside the view are still part of the orbit, they just aren't drawn.
+		px, py := frame.ToPixel(p.X, p.Y)
+		splatter.Splat(out, px, py, 0, 1.0)
+	}
+}
+
+// burnIn returns the point of the orbit of pt warmUp steps after its start.
+func burnIn(pt transforms.ProbabilisticTransform, r *rand.Rand, warmUp int) geometry.XY {
+	p := pt.First()
+	for i := 0; i < warmUp; i++ {
+		p = pt.Next(p, r)
+	}
+	return p
+}
+
+func finite(p geometry.XY) bool {
+	return !math.IsNaN(p.X) && !math.IsNaN(p.Y) && !math.IsInf(p.X, 0) && !math.IsInf(p.Y, 0)
+}
+
+// frameAttractor centers v on the attractor of pt and sets its height to fit it,
+// keeping v's rotation and aspect, from points of a short chaos game.
+// Points are framed where final, if set, moves them to.
+func frameAttractor(v *viewport.Viewport, pt transforms.ProbabilisticTransform, final transforms.Transform, r *rand.Rand, warmUp int) error {
+	sin, cos := math.Sincos(v.Rotation)
+
+	// Coordinates along the horizontal and vertical axes of the view.
+	us := make([]float64, 0, ifsFrameSamples)
+	vs := make([]float64, 0, ifsFrameSamples)
+
+	p := burnIn(pt, r, warmUp)
+	for i := 0; i < ifsFrameSamples; i++ {
+		p = pt.Next(p, r)
+		if !finite(p) {
+			p = burnIn(pt, r, warmUp)
+			continue
+		}
+
+		q := p
+		if final != nil {
+			q = final.Next(p, r)
+			if !finite(q) {
+				continue
+			}
+		}
+		us = append(us, q.X*cos+q.Y*sin)
+		vs = append(vs, -q.X*sin+q.Y*cos)
+	}
+	if len(us) == 0 {
+		return errors.New("the attractor overflowed before it could be framed")
+	}
+
+	uMin, uMax := quantiles(us, ifsFrameOutliers)
+	vMin, vMax := quantiles(vs, ifsFrameOutliers)
+
+	uCenter, vCenter := 0.5*(uMin+uMax), 0.5*(vMin+vMax)
+	v.CenterX = uCenter*cos - vCenter*sin
+	v.CenterY = uCenter*sin + vCenter*cos
+	v.Center = ""
+
+	// The height which fits the attractor's width, given the shape of the view.
+	unit := *v
+	unit.ViewHeight = 1.0
+	widthHeight := (uMax - uMin) / unit.ViewWidth()
+	v.ViewHeight = math.Max(vMax-vMin, widthHeight) * (1.0 + 2.0*ifsFrameMargin)
+	if v.ViewHeight == 0.0 {
+		// The attractor is a single point.
+		v.ViewHeight = 1.0
+	}
+
+	return nil
+}
+
+// quantiles returns the values of xs which fraction of them are below and above.
+// Sorts xs.
+func quantiles(xs []float64, fraction float64) (float64, float64) {
+	slices.Sort(xs)
+	k := int(fraction * float64(len(xs)))
+	return xs[k], xs[len(xs)-1-k]
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/escapetime"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math/cmplx"
+	"math/rand"
+)
+
+// JuliaParams alternates between applying a JuliaN and a Linear transform.
+type JuliaParams struct {
+	C scene.Complex `json:"c"`
+	N scene.Complex `json:"n"`
+
+	Multiply scene.Complex `json:"multiply"`
+	Add      scene.Complex `json:"add"`
+}
+
+var juliaParams = JuliaParams{
+	C:        scene.NewComplex(complex(0.45, -0.575)),
+	N:        scene.Complex{6.0, 0.0},
+	Multiply: scene.NewComplex(cmplx.Rect(1.1, 0.3)),
+}
+
+var juliaScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "julia", Params: &juliaParams},
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		ViewHeight: 2.25,
+	},
+	Sampling: scene.Sampling{
+		SubPixels:       10,
+		MaxIterations:   100,
+		PeriodTolerance: 1e-10,
+	},
+	Coloring: "smooth",
+	ToneMap:  "linear",
+	Palette:  "lightblue",
+}
+
+func juliaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "julia",
+		Short: "Render the escape time of a JuliaN alternating with a Linear transform",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runJulia,
+	}
+
+	flags := cmd.Flags()
+	juliaScene.AddFlags(flags)
+	flags.StringVar(&juliaScene.Coloring, "coloring", juliaScene.Coloring, escapetime.ColoringUsage)
+	flags.StringVar(&juliaScene.Bailout, "bailout", juliaScene.Bailout, escapetime.BailoutUsage)
+	flags.Float64Var(&juliaScene.Sampling.PeriodTolerance, "period-tolerance", juliaScene.Sampling.PeriodTolerance, periodToleranceUsage)
+	flags.IntVar(&juliaScene.Sampling.SubPixels, "subpixels", juliaScene.Sampling.SubPixels, "samples per pixel")
+	flags.Var(&juliaParams.C, "c", "additive constant C of the JuliaN transform, as real,imaginary")
+	flags.Var(&juliaParams.N, "n", "exponent N of the JuliaN transform, as real,imaginary")
+	flags.Var(&juliaParams.Multiply, "multiply", "multiplier of the Linear transform, as real,imaginary")
+	flags.Var(&juliaParams.Add, "add", "additive constant of the Linear transform, as real,imaginary")
+
+	return cmd
+}
+
+func runJulia(cmd *cobra.Command, _ []string) error {
+	sc := &juliaScene
+	params := &juliaParams
+
+	err := loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	coloring, err := escapetime.ParseColoring(sc.Coloring)
+	if err != nil {
+		return err
+	}
+
+	frame, err := sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+	width, height := frame.Width(), frame.Height()
+
+	subPixels := sc.Sampling.SubPixels
+	maxIterations := sc.Sampling.MaxIterations
+
+	f := transforms.Alternate(
+		transforms.JuliaN{C: params.C.Value(), N: params.N.Value()},
+		transforms.Linear{Multiply: params.Multiply.Value(), Add: params.Add.Value()},
+	)
+
+	bailout, err := escapetime.ParseBailout(sc.Bailout, f)
+	if err != nil {
+		return err
+	}
+	if err = escapetime.CheckBailout(coloring, bailout); err != nil {
+		return err
+	}
+
+	options := escapetime.Options{
+		Bailout:       bailout,
+		MaxIterations: maxIterations,
+		PixelSize:     frame.PixelHeight(),
+		Tolerance:     sc.Sampling.PeriodTolerance,
+	}
+
+	brightness := renderPixels(cmd.Context(), width, height, sc.Sampling.Workers, sc.Sampling.Seed, func(r *rand.Rand, x, y int) float64 {
+		b := 0.0
+
+		for s := 0; s < subPixels; s++ {
+			// Slightly jitter points.
+			sx, sy := frame.FromPixel(float64(x)+r.Float64(), float64(y)+r.Float64())
+
+			b += coloring.Value(f, 0, complex(sy, sx), options)
+		}
+
+		return b
+	})
+
+	err = sc.WriteRaw(brightness)
+	if err != nil {
+		return err
+	}
+
+	values := brightness.Channel(0)
+	toneMapper.Apply(values)
+
+	img := palette.Render(pal, values, width, height)
+
+	return sc.Output.WritePNG(img)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/willbeason/tree-fractal/pkg/progress"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+)
+
+// options are the settings shared by every subcommand.
+type options struct {
+	Width, Height int
+	Out           string
+	Raw           string
+	Seed          int64
+	Workers       int
+	MaxIterations int
+	SceneFile     string
+	Progress      time.Duration
+}
+
+var shared options
+
+func mainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diffractal",
+		Short: "Render fractals",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.IntVar(&shared.Width, "width", 2560, "width of the image in pixels")
+	flags.IntVar(&shared.Height, "height", 1440, "height of the image in pixels")
+	flags.StringVarP(&shared.Out, "out", "o", "", "path of the PNG to write; defaults to a timestamped file in out/")
+	flags.StringVar(&shared.Raw, "raw", "", "path to save the raw accumulation buffer to before tone mapping, for use with recolor")
+	flags.Int64Var(&shared.Seed, "seed", 0, "random seed; the same seed and worker count reproduce the same image; picked from the clock if not set here or in --scene")
+	flags.IntVar(&shared.Workers, "workers", runtime.NumCPU(), "number of worker goroutines")
+	flags.IntVar(&shared.MaxIterations, "max-iterations", 0, "maximum iterations per orbit; 0 keeps the subcommand's default")
+	flags.StringVar(&shared.SceneFile, "scene", "", "JSON scene file describing the render; flags override it")
+	flags.DurationVar(&shared.Progress, "progress", 5*time.Second, "how often to report progress on stderr; 0 to only report when done")
+
+	cmd.AddCommand(treeCmd(), juliaCmd(), escapeCmd(), poincareCmd(), paramCmd(), ifsCmd(), flameCmd(), recolorCmd())
+
+	return cmd
+}
+
+// loadScene applies the scene file and then any shared flags set on the command line to sc.
+func loadScene(cmd *cobra.Command, sc *scene.Scene) error {
+	// At this point usage information has already been printed if obviously incorrect.
+	cmd.SilenceUsage = true
+
+	// Seed from the clock unless the scene file or --seed picks a seed, which may be zero.
+	clock := time.Now().UnixNano()
+	sc.Sampling.Seed = clock
+
+	err := sc.Apply(shared.SceneFile, cmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	shared.apply(cmd.Flags(), sc)
+
+	err = sc.Viewport.ResolveCenter()
+	if err != nil {
+		return err
+	}
+
+	if sc.Sampling.Seed == clock {
+		// Report the seed so that the render can be reproduced.
+		fmt.Fprintf(os.Stderr, "seed: %d\n", sc.Sampling.Seed)
+	}
+	if sc.Sampling.Workers <= 0 {
+		sc.Sampling.Workers = shared.Workers
+	}
+
+	return nil
+}
+
+// startProgress starts reporting progress toward total units of work on stderr.
+func startProgress(unit string, total int64) *progress.Reporter {
+	return progress.Start(os.Stderr, unit, total, shared.Progress)
+}
+
+// stopProgress stops reporting progress, noting on stderr if the render was
+// cancelled so that what follows is a partial image.
+func stopProgress(ctx context.Context, r *progress.Reporter) {
+	r.Stop()
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "interrupted; writing partial image")
+	}
+}
+
+// apply copies the options set on the command line into sc.
+func (o options) apply(flags *pflag.FlagSet, sc *scene.Scene) {
+	if flags.Changed("width") {
+		sc.Viewport.Width = o.Width
+	}
+	if flags.Changed("height") {
+		sc.Viewport.Height = o.Height
+	}
+	if flags.Changed("out") {
+		sc.Output.Path = o.Out
+	}
+	if flags.Changed("raw") {
+		sc.Output.Raw = o.Raw
+	}
+	if flags.Changed("seed") {
+		sc.Sampling.Seed = o.Seed
+	}
+	if flags.Changed("workers") {
+		sc.Sampling.Workers = o.Workers
+	}
+	if flags.Changed("max-iterations") {
+		sc.Sampling.MaxIterations = o.MaxIterations
+	}
+}
+
+func main() {
+	// Cancel the render on Ctrl-C so that workers can stop and write what they have.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Restore the default handler once interrupted, so that a second Ctrl-C kills a
+	// render which is slow to stop, such as one writing a large checkpoint.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := mainCmd().ExecuteContext(ctx)
+	if err != nil {
+		// At this point the error has already been printed; no need to print again.
+		stop()
+		os.Exit(1)
+	}
+}
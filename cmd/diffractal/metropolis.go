@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/checkpoint"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math/rand"
+	"sync"
+)
+
+// metropolisChunk is the number of steps a chain takes between checks for whether to checkpoint.
+const metropolisChunk = 1000
+
+// metropolisLarge is the probability that a step proposes a new starting point anywhere
+// in the starting region rather than one near the current one.
+const metropolisLarge = 0.2
+
+// metropolisTries is the number of uniformly drawn starting points a chain tries
+// before giving up on finding an orbit which lands in the view.
+const metropolisTries = 1_000_000
+
+// metropolisChain is the checkpointed state of a metropolis chain.
+type metropolisChain struct {
+	// Done is the number of steps taken so far, including warm-up.
+	Done int
+
+	// Z is the current starting point of the chain.
+	Z complex128
+
+	// Total is the sum of the contributions of the Proposals uniformly drawn starting points.
+	Total     float64
+	Proposals int64
+
+	// RNG is the state of the chain's random number generator.
+	RNG []byte
+}
+
+// metropolisState is the checkpointed state of an escape render with the metropolis sampler.
+type metropolisState struct {
+	Chains []metropolisChain
+
+	// Frequencies is a Snapshot of the frequencies accumulated so far.
+	Frequencies []byte
+}
+
+// sampleMetropolis accumulates into frequencies the orbits started from points chosen
+// by Metropolis-Hastings chains, one per worker, of Sampling.Samples steps in total.
+//
+// The chains favor starting points in proportion to their contribution: how many
+// points of their orbits land in the view. Each step draws the current orbit scaled
+// down by its contribution, so that favored orbits are drawn no brighter for being
+// drawn more often. Frequencies are then scaled by the mean contribution of uniformly
+// drawn starting points, estimated from the proposals which draw them, so that they
+// match what a grid sampler would draw from as many starting points.
+func sampleMetropolis(ctx context.Context, sc *scene.Scene, render *escapeRender, startFrame viewport.Frame, frequencies *accum.Sum, store *checkpoint.Store) (*accum.Buffer, error) {
+	parallel := sc.Sampling.Workers
+	steps := int(sc.Sampling.Samples / int64(parallel))
+	warmUp := sc.Sampling.WarmUp
+	sigma := escapeParams.Mutation * sc.Viewport.ViewHeight
+
+	state := metropolisState{Chains: make([]metropolisChain, parallel)}
+	resumed, err := store.Load(&state)
+	if err != nil {
+		return nil, err
+	}
+	if resumed {
+		if len(state.Chains) != parallel {
+			return nil, fmt.Errorf("checkpoint has %d chains, want %d", len(state.Chains), parallel)
+		}
+		err = frequencies.Restore(state.Frequencies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	done := startProgress("samples", int64((warmUp+steps)*parallel))
+	for _, chain := range state.Chains {
+		done.Skip(int64(chain.Done))
+	}
+
+	saver := newCheckpointer(store, frequencies, func(data []byte) any {
+		return &metropolisState{Chains: state.Chains, Frequencies: data}
+	})
+
+	errs := make([]error, parallel)
+
+	wg := sync.WaitGroup{}
+	wg.Add(parallel)
+	for i := 0; i < parallel; i++ {
+		go func() {
+			defer wg.Done()
+
+			src := rng.NewSource(sc.Sampling.Seed, i)
+			r := rand.New(src)
+			shard := frequencies.Shard()
+
+			// The current and proposed orbits, swapped when a proposal is accepted.
+			path := make([]complex128, render.maxIterations)
+			counts := make([]int, len(render.windows))
+			proposedPath := make([]complex128, render.maxIterations)
+			proposedCounts := make([]int, len(render.windows))
+
+			uniform := func() complex128 {
+				sx, sy := startFrame.FromPixel(r.Float64()*float64(startFrame.Width()), r.Float64()*float64(startFrame.Height()))
+				return complex(sy, sx)
+			}
+
+			// The chain is recorded in state.Chains only as its shard is flushed.
+			chain := state.Chains[i]
+			if resumed {
+				errs[i] = src.UnmarshalBinary(chain.RNG)
+				if errs[i] != nil {
+					return
+				}
+			} else {
+				for chain.Total == 0.0 {
+					if chain.Proposals == metropolisTries {
+						errs[i] = errors.New("found no orbits landing in the view to start the metropolis sampler from")
+						return
+					}
+					chain.Z = uniform()
+					render.trace(chain.Z, path, counts)
+					chain.Total += float64(render.visible(path, counts))
+					chain.Proposals++
+				}
+			}
+
+			// The orbit of the current point, which checkpoints don't keep.
+			most, n := render.trace(chain.Z, path, counts)
+			contribution := render.visible(path, counts)
+
+			// record records the chain as it is when shard is flushed.
+			record := func() {
+				// Marshalling a PCG never fails.
+				chain.RNG, _ = src.MarshalBinary()
+				state.Chains[i] = chain
+			}
+
+			for chain.Done < warmUp+steps && ctx.Err() == nil {
+				chunk := min(metropolisChunk, warmUp+steps-chain.Done)
+				for s := 0; s < chunk; s++ {
+					large := r.Float64() < metropolisLarge
+
+					var z complex128
+					if large {
+						z = uniform()
+					} else {
+						z = chain.Z + complex(r.NormFloat64()*sigma, r.NormFloat64()*sigma)
+					}
+
+					// Starting points outside the starting region are never drawn.
+					proposed := 0
+					proposedMost, proposedN := 0, 0
+					if _, inside := startFrame.Index(imag(z), real(z)); inside {
+						proposedMost, proposedN = render.trace(z, proposedPath, proposedCounts)
+						proposed = render.visible(proposedPath, proposedCounts)
+					}
+
+					if large {
+						chain.Total += float64(proposed)
+						chain.Proposals++
+					}
+
+					// Both kinds of mutation are symmetric, so proposals are accepted with the
+					// ratio of their contributions.
+					if proposed >= contribution || r.Float64()*float64(contribution) < float64(proposed) {
+						chain.Z = z
+						path, proposedPath = proposedPath, path
+						counts, proposedCounts = proposedCounts, counts
+						most, n, contribution = proposedMost, proposedN, proposed
+					}
+
+					if chain.Done+s >= warmUp {
+						render.draw(shard, path, most, n, counts, 1.0/float64(contribution))
+					}
+				}
+
+				chain.Done += chunk
+				done.Add(int64(chunk))
+
+				if shard.Full() || saver.due() {
+					errs[i] = saver.flush(shard, record)
+					if errs[i] != nil {
+						return
+					}
+				}
+			}
+
+			// Record where an interrupted chain stopped so that it can be resumed.
+			shard.Flush(record)
+		}()
+	}
+
+	wg.Wait()
+	stopProgress(ctx, done)
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() != nil {
+		err = saver.save()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	total, proposals := 0.0, int64(0)
+	for _, chain := range state.Chains {
+		total += chain.Total
+		proposals += chain.Proposals
+	}
+
+	buffer := frequencies.Buffer()
+	scale := total / float64(proposals)
+	for i := range buffer.Data {
+		buffer.Data[i] *= scale
+	}
+
+	return buffer, nil
+}
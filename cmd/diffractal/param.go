@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/escapetime"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math"
+	"math/rand"
+)
+
+// ParamParams choose the family of maps to render and the plane to render it in.
+type ParamParams struct {
+	// Family is one of familyUsage.
+	Family string `json:"family"`
+
+	// N is the exponent of the multibrot, julia, and mcmullen families.
+	// The mcmullen family rounds it to an integer.
+	N scene.Complex `json:"n"`
+
+	// M is the exponent of the denominator of the mcmullen family.
+	M int `json:"m"`
+
+	// Q multiplies the previous point of the orbit in the phoenix family.
+	Q scene.Complex `json:"q"`
+
+	// Multiply and Add are the Linear transform of the "julia" family.
+	Multiply scene.Complex `json:"multiply"`
+	Add      scene.Complex `json:"add"`
+
+	// Plane is "parameter", to render the family over its parameter, or "dynamical",
+	// to render the Julia set of the map at Julia.
+	Plane string `json:"plane,omitempty"`
+
+	// Julia is a parameter whose Julia set is rendered. In the parameter plane it is
+	// rendered alongside, if set.
+	Julia *scene.Complex `json:"julia,omitempty"`
+
+	// JuliaViewHeight is the vertical extent of the Julia set render, which is centered on the origin.
+	JuliaViewHeight float64 `json:"juliaViewHeight,omitempty"`
+
+	// Deep renders by perturbation around a high-precision reference orbit, for zooms
+	// too deep for complex128. Only the z^2 + c family supports it.
+	Deep bool `json:"deep,omitempty"`
+}
+
+const familyUsage = "mandelbrot (z^2 + c), multibrot (z^N + c), julia (z^N + c alternating with a Linear transform), " +
+	"burning-ship, tricorn, celtic, magnet1, magnet2, phoenix (z^2 + c + Q times the previous z), " +
+	"or mcmullen (z^N + c/z^M)"
+
+// Maps returns the family of maps p describes.
+func (p ParamParams) Maps() (transforms.Family, error) {
+	switch p.Family {
+	case "mandelbrot":
+		return transforms.Mandelbrot{}, nil
+	case "multibrot":
+		return transforms.Multibrot{N: p.N.Value()}, nil
+	case "julia":
+		return transforms.AlternateFamily{
+			N:     p.N.Value(),
+			Other: transforms.Linear{Multiply: p.Multiply.Value(), Add: p.Add.Value()},
+		}, nil
+	case "burning-ship":
+		return transforms.BurningShip{}, nil
+	case "tricorn":
+		return transforms.Tricorn{}, nil
+	case "celtic":
+		return transforms.Celtic{}, nil
+	case "magnet1":
+		return transforms.Magnet1{}, nil
+	case "magnet2":
+		return transforms.Magnet2{}, nil
+	case "phoenix":
+		return transforms.Phoenix{P: p.Q.Value()}, nil
+	case "mcmullen":
+		n := int(math.Round(real(p.N.Value())))
+		if n < 1 || p.M < 1 {
+			return nil, fmt.Errorf("the mcmullen family needs exponents N and M of at least 1, got N=%d and M=%d", n, p.M)
+		}
+		return transforms.McMullen{N: n, M: p.M}, nil
+	default:
+		return nil, fmt.Errorf("unknown family %q, want %s", p.Family, familyUsage)
+	}
+}
+
+var paramParams = ParamParams{
+	Family:          "multibrot",
+	N:               scene.Complex{2.0, 0.0},
+	M:               1,
+	Q:               scene.Complex{-0.5, 0.0},
+	Plane:           "parameter",
+	Multiply:        juliaParams.Multiply,
+	JuliaViewHeight: 3.0,
+}
+
+// paramJulia holds --julia until it is known whether the flag was set.
+var paramJulia scene.Complex
+
+var paramScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "param", Params: &paramParams},
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		CenterX:    -0.75,
+		ViewHeight: 2.5,
+	},
+	Sampling: scene.Sampling{
+		SubPixels:       4,
+		MaxIterations:   200,
+		PeriodTolerance: 1e-10,
+	},
+	Coloring: "smooth",
+	ToneMap:  "log",
+	Palette:  "inferno",
+}
+
+func paramCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "param",
+		Short: "Render the parameter plane of a family of maps from their critical points, or one of its Julia sets",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runParam,
+	}
+
+	flags := cmd.Flags()
+	paramScene.AddFlags(flags)
+	flags.StringVar(&paramScene.Coloring, "coloring", paramScene.Coloring, escapetime.ColoringUsage+"; distance is only supported in the dynamical plane")
+	flags.StringVar(&paramScene.Bailout, "bailout", paramScene.Bailout, escapetime.BailoutUsage+"; deep zooms need radius or norm")
+	flags.Float64Var(&paramScene.Sampling.PeriodTolerance, "period-tolerance", paramScene.Sampling.PeriodTolerance, periodToleranceUsage)
+	flags.IntVar(&paramScene.Sampling.SubPixels, "subpixels", paramScene.Sampling.SubPixels, "samples per pixel")
+	flags.StringVar(&paramParams.Family, "family", paramParams.Family, "family of maps: "+familyUsage)
+	flags.Var(&paramParams.N, "n", "exponent N of the family, as real,imaginary")
+	flags.IntVar(&paramParams.M, "m", paramParams.M, "exponent M of the denominator of the mcmullen family")
+	flags.Var(&paramParams.Q, "q", "multiplier Q of the previous point in the phoenix family, as real,imaginary")
+	flags.StringVar(&paramParams.Plane, "plane", paramParams.Plane, "plane to render: parameter, or dynamical for the Julia set at --julia")
+	flags.Var(&paramParams.Multiply, "multiply", "multiplier of the Linear transform of the julia family, as real,imaginary")
+	flags.Var(&paramParams.Add, "add", "additive constant of the Linear transform of the julia family, as real,imaginary")
+	flags.Var(&paramJulia, "julia", "parameter of the Julia set to render, as real,imaginary; in the parameter plane it is written alongside, suffixed with -julia")
+	flags.Float64Var(&paramParams.JuliaViewHeight, "julia-view-height", paramParams.JuliaViewHeight, "vertical extent of the Julia set render")
+	flags.BoolVar(&paramParams.Deep, "deep", paramParams.Deep, "render by perturbation around a high-precision reference orbit at --center, for zooms beyond about 1e-13; needs the mandelbrot family, or multibrot with n=2")
+
+	return cmd
+}
+
+func runParam(cmd *cobra.Command, _ []string) error {
+	sc := &paramScene
+	params := &paramParams
+
+	err := loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("julia") {
+		params.Julia = &paramJulia
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	coloring, err := escapetime.ParseColoring(sc.Coloring)
+	if err != nil {
+		return err
+	}
+
+	family, err := params.Maps()
+	if err != nil {
+		return err
+	}
+
+	frame, err := sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+
+	var counts *accum.Buffer
+	switch {
+	case params.Deep:
+		counts, err = renderDeep(cmd.Context(), sc, frame, family, coloring)
+	case params.Plane == "parameter":
+		if _, ok := coloring.(escapetime.Distance); ok {
+			return errors.New("distance coloring is only supported in the dynamical plane")
+		}
+		counts, err = renderParameterPlane(cmd.Context(), sc, frame, family, coloring)
+	case params.Plane == "dynamical":
+		if params.Julia == nil {
+			return errors.New("rendering the dynamical plane requires --julia")
+		}
+		counts, err = renderDynamicalPlane(cmd.Context(), sc, frame, family.At(params.Julia.Value()), coloring)
+	default:
+		return fmt.Errorf("unknown plane %q, want parameter or dynamical", params.Plane)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = sc.WriteRaw(counts)
+	if err != nil {
+		return err
+	}
+
+	if params.Plane == "parameter" && params.Julia != nil {
+		// Resolve the output path first so the linked render is named after it.
+		sc.Output.Resolve()
+
+		err = writeLinkedJulia(cmd.Context(), sc, family.At(params.Julia.Value()), coloring, toneMapper, pal)
+		if err != nil {
+			return err
+		}
+	}
+
+	values := counts.Channel(0)
+	toneMapper.Apply(values)
+
+	img := palette.Render(pal, values, frame.Width(), frame.Height())
+
+	return sc.Output.WritePNG(img)
+}
+
+// renderParameterPlane renders the critical orbits of each map of family, by their
+// escape time or, with period coloring, the cycles they fall into.
+// Trap colorings follow the orbit of the first critical point.
+func renderParameterPlane(ctx context.Context, sc *scene.Scene, frame viewport.Frame, family transforms.Family, coloring escapetime.Coloring) (*accum.Buffer, error) {
+	if len(transforms.CriticalPoints(family.At(0))) == 0 {
+		return nil, errors.New("family has no critical points to iterate from")
+	}
+	bailout, err := escapetime.ParseBailout(sc.Bailout, family.At(0))
+	if err != nil {
+		return nil, err
+	}
+
+	_, period := coloring.(escapetime.Period)
+	trap, isTrap := coloring.(escapetime.Trapped)
+	options := escapetime.Options{
+		Bailout:       bailout,
+		MaxIterations: sc.Sampling.MaxIterations,
+		Tolerance:     sc.Sampling.PeriodTolerance,
+	}
+
+	subPixels := sc.Sampling.SubPixels
+	maxIterations := sc.Sampling.MaxIterations
+	tolerance := sc.Sampling.PeriodTolerance
+
+	// A parameter is outside the connectedness locus once any critical orbit escapes,
+	// so its escape time is that of the first critical orbit to escape.
+	// Inside, it is colored by the first critical orbit found to be periodic.
+	counts := renderPixels(ctx, frame.Width(), frame.Height(), sc.Sampling.Workers, sc.Sampling.Seed, func(r *rand.Rand, x, y int) float64 {
+		b := 0.0
+
+		for s := 0; s < subPixels; s++ {
+			// Slightly jitter points.
+			c := frame.FromPixelZ(float64(x)+r.Float64(), float64(y)+r.Float64())
+			f := family.At(c)
+
+			if isTrap {
+				critical := transforms.CriticalPoints(f)[0]
+				b += trap.Value(f, critical.Step, critical.Z, options)
+				continue
+			}
+
+			first, interior := math.Inf(1), 0.0
+			for _, critical := range transforms.CriticalPoints(f) {
+				orbit := escapetime.Iterate(f, critical.Step, critical.Z, bailout, maxIterations, tolerance)
+				if orbit.Escaped {
+					first = math.Min(first, escapetime.EscapeCount(f, critical.Step, orbit, bailout))
+				} else if interior == 0.0 {
+					interior = escapetime.PeriodValue(orbit)
+				}
+			}
+
+			switch {
+			case math.IsInf(first, 1) && period:
+				b += interior
+			case !math.IsInf(first, 1) && !period:
+				b += first
+			}
+		}
+
+		return b
+	})
+
+	return counts, nil
+}
+
+// renderDynamicalPlane colors orbits of f from each point.
+func renderDynamicalPlane(ctx context.Context, sc *scene.Scene, frame viewport.Frame, f transforms.ComplexMap, coloring escapetime.Coloring) (*accum.Buffer, error) {
+	if _, ok := coloring.(escapetime.Distance); ok && !escapetime.Differentiable(f) {
+		return nil, fmt.Errorf("distance coloring needs a differentiable map, but the %s family isn't", paramParams.Family)
+	}
+
+	bailout, err := escapetime.ParseBailout(sc.Bailout, f)
+	if err != nil {
+		return nil, err
+	}
+	if err = escapetime.CheckBailout(coloring, bailout); err != nil {
+		return nil, err
+	}
+
+	options := escapetime.Options{
+		Bailout:       bailout,
+		MaxIterations: sc.Sampling.MaxIterations,
+		PixelSize:     frame.PixelHeight(),
+		Tolerance:     sc.Sampling.PeriodTolerance,
+	}
+	subPixels := sc.Sampling.SubPixels
+
+	counts := renderPixels(ctx, frame.Width(), frame.Height(), sc.Sampling.Workers, sc.Sampling.Seed, func(r *rand.Rand, x, y int) float64 {
+		b := 0.0
+
+		for s := 0; s < subPixels; s++ {
+			// Slightly jitter points.
+			z := frame.FromPixelZ(float64(x)+r.Float64(), float64(y)+r.Float64())
+
+			b += coloring.Value(f, 0, z, options)
+		}
+
+		return b
+	})
+
+	return counts, nil
+}
+
+// writeLinkedJulia renders the Julia set of f at the resolution of sc, centered on the origin.
+func writeLinkedJulia(ctx context.Context, sc *scene.Scene, f transforms.ComplexMap, coloring escapetime.Coloring, toneMapper tonemap.Operator, pal palette.Palette) error {
+	view := sc.Viewport
+	view.CenterX, view.CenterY, view.Center = 0.0, 0.0, ""
+	view.ViewHeight = paramParams.JuliaViewHeight
+	view.Rotation = 0.0
+	frame, err := view.Frame()
+	if err != nil {
+		return fmt.Errorf("julia set view: %w", err)
+	}
+
+	counts, err := renderDynamicalPlane(ctx, sc, frame, f, coloring)
+	if err != nil {
+		return err
+	}
+
+	values := counts.Channel(0)
+	toneMapper.Apply(values)
+
+	img := palette.Render(pal, values, frame.Width(), frame.Height())
+
+	return sc.Output.Linked("-julia").WritePNG(img)
+}
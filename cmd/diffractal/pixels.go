@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"math/rand"
+	"sync"
+)
+
+const periodToleranceUsage = "how close an orbit must return to an earlier point to be considered periodic and stop early; 0 disables the check"
+
+// renderPixels sets every pixel of a width by height buffer to pixel(r, x, y),
+// computing rows in parallel on workers goroutines.
+//
+// Each row has its own random stream derived from seed, so the result doesn't depend
+// on which worker takes which row. Stops handing out rows when ctx is cancelled;
+// rows already taken are finished and the rest are left zero.
+func renderPixels(ctx context.Context, width, height, workers int, seed int64, pixel func(r *rand.Rand, x, y int) float64) *accum.Buffer {
+	buffer := accum.New(width, height, 1)
+	rows := startProgress("rows", int64(height))
+
+	yChannel := make(chan int)
+
+	go func() {
+		defer close(yChannel)
+		for y := 0; y < height; y++ {
+			select {
+			case yChannel <- y:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for y := range yChannel {
+				r := rng.New(seed, y)
+				// Rows are disjoint, so workers never write the same pixel.
+				for x := 0; x < width; x++ {
+					buffer.Data[x+y*width] = pixel(r, x, y)
+				}
+
+				rows.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	stopProgress(ctx, rows)
+
+	return buffer
+}
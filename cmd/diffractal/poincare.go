@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/diffeq-go/pkg/equations"
+	"github.com/willbeason/diffeq-go/pkg/models"
+	"github.com/willbeason/diffeq-go/pkg/solvers/order2"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+var spring = models.DuffingOscillator{
+	Delta:     0.018,
+	Alpha:     0.22,
+	Beta:      3.3,
+	Gamma:     32.657,
+	Frequency: 2.03,
+}
+
+var poincareScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "poincare", Params: &spring},
+	// Spans velocities in [-30, 30] horizontally and positions in [0.2, 4] vertically,
+	// from the top of the image down.
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		CenterX:    0.0,
+		CenterY:    2.1,
+		ViewHeight: 3.8,
+		Aspect:     (60.0 / 2560.0) / (3.8 / 1440.0),
+		FlipY:      true,
+	},
+	Sampling: scene.Sampling{
+		// 1e6 cycles per worker => 15 seconds
+		Samples: 1e10,
+		WarmUp:  10000,
+	},
+	ToneMap: "equalize",
+	Palette: "poincare",
+}
+
+// poincareChunk is the number of cycles a worker simulates between checks for whether to checkpoint.
+const poincareChunk = 10000
+
+// poincareWorker is the checkpointed state of a poincare worker.
+type poincareWorker struct {
+	// Y and YP are the position and velocity of the oscillator.
+	Y, YP float64
+
+	// RNG is the state of the worker's random number generator.
+	RNG []byte
+
+	// Done is the number of cycles plotted so far.
+	Done int
+}
+
+// poincareState is the checkpointed state of a poincare render.
+type poincareState struct {
+	Workers []poincareWorker
+
+	// Counts is a Snapshot of the counts accumulated so far.
+	Counts []byte
+}
+
+func work(eq equations.SecondOrder, solver order2.Solver, frame viewport.Frame, r *rand.Rand, y0, yp0, h float64, n int, out *accum.Shard) (float64, float64) {
+	y := y0
+	yp := yp0
+
+	dx, dy := frame.PixelWidth(), frame.PixelHeight()
+
+	for i := 0; i < n; i++ {
+		y, yp = order2.Solve(solver, eq, 0.0, y, yp, h, 50)
+		if out != nil {
+			px, py := frame.ToPixel(yp, y)
+			accum.Nearest{}.Splat(out, px, py, 0, 1.0)
+		}
+
+		y += (-0.5 + r.Float64()) * dy * 2
+		yp += (-0.5 + r.Float64()) * dx * 2
+	}
+
+	return y, yp
+}
+
+func runPoincare(cmd *cobra.Command, _ []string) error {
+	sc := &poincareScene
+
+	err := resumeScene()
+	if err != nil {
+		return err
+	}
+
+	err = loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	frame, err := sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+	width, height := frame.Width(), frame.Height()
+
+	sum := accum.NewSum(width, height, 1)
+
+	store, err := openCheckpoint(sc)
+	if err != nil {
+		return err
+	}
+
+	nWorkers := sc.Sampling.Workers
+	cycles := int(sc.Sampling.Samples / int64(nWorkers))
+
+	state := poincareState{Workers: make([]poincareWorker, nWorkers)}
+	resumed, err := store.Load(&state)
+	if err != nil {
+		return err
+	}
+	if resumed {
+		if len(state.Workers) != nWorkers {
+			return fmt.Errorf("checkpoint has %d workers, want %d", len(state.Workers), nWorkers)
+		}
+		err = sum.Restore(state.Counts)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx := cmd.Context()
+	done := startProgress("cycles", int64(cycles*nWorkers))
+	for _, worker := range state.Workers {
+		done.Skip(int64(worker.Done))
+	}
+
+	saver := newCheckpointer(store, sum, func(data []byte) any {
+		return &poincareState{Workers: state.Workers, Counts: data}
+	})
+
+	wg := sync.WaitGroup{}
+	wg.Add(nWorkers)
+	errs := make([]error, nWorkers)
+
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			defer wg.Done()
+
+			src := rng.NewSource(sc.Sampling.Seed, i)
+			r := rand.New(src)
+			shard := sum.Shard()
+
+			h := 2 * math.Pi / spring.Frequency
+			rk4 := order2.NewRungeKuttaSolver(order2.RK4())
+
+			// The worker is recorded in state.Workers only as its shard is flushed.
+			worker := state.Workers[i]
+			if resumed {
+				errs[i] = src.UnmarshalBinary(worker.RNG)
+				if errs[i] != nil {
+					return
+				}
+			} else {
+				y0 := sc.Viewport.CenterY + 10*frame.PixelHeight()*r.Float64()
+				yp0 := sc.Viewport.CenterX + 10*frame.PixelWidth()*r.Float64()
+				worker.Y, worker.YP = work(spring.Acceleration, rk4, frame, r, y0, yp0, h, sc.Sampling.WarmUp, nil)
+			}
+
+			// record records the worker as it is when shard is flushed.
+			record := func() {
+				// Marshalling a PCG never fails.
+				worker.RNG, _ = src.MarshalBinary()
+				state.Workers[i] = worker
+			}
+
+			for worker.Done < cycles && ctx.Err() == nil {
+				n := min(poincareChunk, cycles-worker.Done)
+				worker.Y, worker.YP = work(spring.Acceleration, rk4, frame, r, worker.Y, worker.YP, h, n, shard)
+				worker.Done += n
+				done.Add(int64(n))
+
+				if shard.Full() || saver.due() {
+					errs[i] = saver.flush(shard, record)
+					if errs[i] != nil {
+						return
+					}
+				}
+			}
+
+			// Record where an interrupted worker stopped so that it can be resumed.
+			shard.Flush(record)
+		}()
+	}
+
+	wg.Wait()
+	stopProgress(ctx, done)
+	err = errors.Join(errs...)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		err = saver.save()
+		if err != nil {
+			return err
+		}
+	}
+
+	buffer := sum.Buffer()
+
+	err = sc.WriteRaw(buffer)
+	if err != nil {
+		return err
+	}
+
+	counts := buffer.Channel(0)
+	toneMapper.Apply(counts)
+
+	img := palette.Render(pal, counts, width, height)
+
+	return sc.Output.WritePNG(img)
+}
+
+func poincareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "poincare",
+		Short: "Render the Poincare section of a driven Duffing oscillator",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runPoincare,
+	}
+
+	flags := cmd.Flags()
+	poincareScene.AddFlags(flags)
+	addCheckpointFlags(flags)
+	flags.Int64Var(&poincareScene.Sampling.Samples, "samples", poincareScene.Sampling.Samples, "total driving cycles simulated across all workers")
+	flags.IntVar(&poincareScene.Sampling.WarmUp, "warm-up", poincareScene.Sampling.WarmUp, "cycles each worker simulates before plotting")
+	flags.Float64Var(&spring.Delta, "delta", spring.Delta, "drag force of the oscillator")
+	flags.Float64Var(&spring.Alpha, "alpha", spring.Alpha, "spring constant of the oscillator")
+	flags.Float64Var(&spring.Beta, "beta", spring.Beta, "nonlinear force response of the oscillator")
+	flags.Float64Var(&spring.Gamma, "gamma", spring.Gamma, "magnitude of the driving force")
+	flags.Float64Var(&spring.Frequency, "frequency", spring.Frequency, "frequency of the driving force")
+
+	return cmd
+}
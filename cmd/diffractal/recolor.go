@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+)
+
+var recolorOptions struct {
+	ToneMap string
+	Palette string
+	Channel int
+}
+
+func recolorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recolor RAW",
+		Short: "Tone map and color a raw buffer saved with --raw without rendering it again",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRecolor,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&recolorOptions.ToneMap, "tonemap", "", tonemap.Usage+"; defaults to the one the buffer was rendered with")
+	flags.StringVar(&recolorOptions.Palette, "palette", "", palette.Usage+"; defaults to the one the buffer was rendered with")
+	flags.IntVar(&recolorOptions.Channel, "channel", 0, "channel of the buffer to color; by default, "+
+		"buffers of escape renders with nebula channels are mixed in their colors as they were rendered")
+
+	return cmd
+}
+
+func runRecolor(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	sc, buf, err := scene.ReadRaw(args[0])
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("tonemap") {
+		sc.ToneMap = recolorOptions.ToneMap
+	}
+	if flags.Changed("palette") {
+		sc.Palette = recolorOptions.Palette
+	}
+	// Never write over the original render unless asked to.
+	sc.Output.Path = shared.Out
+
+	if sc.Fractal.Kind == "flame" {
+		// The colours of a flame are in its buffer, and its tone map in its params.
+		if flags.Changed("tonemap") || flags.Changed("palette") || flags.Changed("channel") {
+			return errors.New("flame buffers hold summed colours rather than values for a palette; " +
+				"they are recolored with the tone map they were rendered with, and take no --tonemap, --palette, or --channel")
+		}
+
+		var params FlameParams
+		err = decodeParams(sc, &params)
+		if err != nil {
+			return err
+		}
+
+		img, err := params.render(buf, sc.Sampling.Samples)
+		if err != nil {
+			return err
+		}
+		return sc.Output.WritePNG(img)
+	}
+
+	if recolorOptions.Channel < 0 || recolorOptions.Channel >= buf.Channels {
+		return fmt.Errorf("channel %d out of range; buffer has %d", recolorOptions.Channel, buf.Channels)
+	}
+
+	channels, err := escapeChannels(sc)
+	if err != nil {
+		return err
+	}
+	if len(channels) == buf.Channels && !flags.Changed("channel") {
+		img, err := mixChannels(buf, channels, sc.ToneMap)
+		if err != nil {
+			return err
+		}
+		return sc.Output.WritePNG(img)
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	values := buf.Channel(recolorOptions.Channel)
+	toneMapper.Apply(values)
+
+	img := palette.Render(pal, values, buf.Width, buf.Height)
+
+	return sc.Output.WritePNG(img)
+}
+
+// escapeChannels returns the nebula channels of the escape render sc describes,
+// or nil if sc isn't an escape render or draws a single channel.
+func escapeChannels(sc *scene.Scene) ([]EscapeChannel, error) {
+	if sc.Fractal.Kind != "escape" {
+		return nil, nil
+	}
+
+	var params EscapeParams
+	err := decodeParams(sc, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	return params.Channels, nil
+}
+
+// decodeParams decodes the params of sc into params, which should be a pointer to
+// the params type of sc's Kind. ReadRaw decodes params generically, since it
+// doesn't know their type.
+func decodeParams(sc *scene.Scene, params any) error {
+	data, err := json.Marshal(sc.Fractal.Params)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, params)
+	if err != nil {
+		return fmt.Errorf("decoding %s params: %w", sc.Fractal.Kind, err)
+	}
+
+	return nil
+}
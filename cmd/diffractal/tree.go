@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/progress"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"github.com/willbeason/tree-fractal/pkg/scene"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/tree"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// TreeParams choose the tree generator and its shape.
+type TreeParams struct {
+	// Generator is one of "symmetric", "balanced", or "random".
+	Generator string `json:"generator"`
+
+	Layers int `json:"layers"`
+
+	// Angle is the branch angle of symmetric and balanced trees, in radians.
+	Angle float64 `json:"angle"`
+
+	// PLeft is the proportion of balanced trees given to the left branch.
+	PLeft float64 `json:"pLeft"`
+}
+
+// Tree builds the tree described by p.
+func (p TreeParams) Tree(r *rand.Rand) (*tree.Tree, error) {
+	switch p.Generator {
+	case "symmetric":
+		return tree.Symmetric(p.Layers, p.Angle), nil
+	case "balanced":
+		return tree.BalancedConstant(p.Layers, p.Angle, p.PLeft), nil
+	case "random":
+		return tree.RandomBalanced(p.Layers, r), nil
+	default:
+		return nil, fmt.Errorf("unknown tree generator %q", p.Generator)
+	}
+}
+
+var treeParams = TreeParams{
+	Generator: "symmetric",
+	Layers:    20,
+	Angle:     0.6,
+	PLeft:     0.4,
+}
+
+var treeScene = scene.Scene{
+	Version: scene.Version,
+	Fractal: scene.Fractal{Kind: "tree", Params: &treeParams},
+	Viewport: viewport.Viewport{
+		Width:      2560,
+		Height:     1440,
+		CenterX:    0.5,
+		CenterY:    2.5,
+		ViewHeight: 6.0,
+	},
+	Sampling: scene.Sampling{
+		Samples: 1e7,
+	},
+	ToneMap: "linear",
+	Palette: "gray",
+	Output:  scene.Output{Path: "out.png"},
+}
+
+func treeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tree",
+		Short: "Render the density of random walks over a branching tree fractal",
+		Args:  cobra.ExactArgs(0),
+		RunE:  runTree,
+	}
+
+	flags := cmd.Flags()
+	treeScene.AddFlags(flags)
+	flags.Int64Var(&treeScene.Sampling.Samples, "samples", treeScene.Sampling.Samples, "number of points plotted")
+	flags.StringVar(&treeParams.Generator, "generator", treeParams.Generator, "tree generator: symmetric, balanced, or random")
+	flags.IntVar(&treeParams.Layers, "layers", treeParams.Layers, "number of branching layers")
+	flags.Float64Var(&treeParams.Angle, "angle", treeParams.Angle, "branch angle of symmetric and balanced trees in radians")
+	flags.Float64Var(&treeParams.PLeft, "p-left", treeParams.PLeft, "proportion of balanced trees given to the left branch")
+
+	return cmd
+}
+
+func runTree(cmd *cobra.Command, _ []string) error {
+	sc := &treeScene
+	params := &treeParams
+
+	err := loadScene(cmd, sc)
+	if err != nil {
+		return err
+	}
+
+	toneMapper, err := tonemap.Parse(sc.ToneMap)
+	if err != nil {
+		return err
+	}
+
+	pal, err := palette.Parse(sc.Palette)
+	if err != nil {
+		return err
+	}
+
+	fractal, err := params.Tree(rng.New(sc.Sampling.Seed))
+	if err != nil {
+		return err
+	}
+	fractal = &tree.Tree{
+		LeftP:      1.0,
+		LeftAngle:  0.0,
+		RightAngle: 0.0,
+		Left:       fractal,
+		Right:      nil,
+	}
+
+	frame, err := sc.Viewport.Frame()
+	if err != nil {
+		return err
+	}
+	width, height := frame.Width(), frame.Height()
+
+	sum := accum.NewSum(width, height, 1)
+
+	ctx := cmd.Context()
+	done := startProgress("samples", sc.Sampling.Samples)
+
+	nWorkers := sc.Sampling.Workers
+
+	wg := sync.WaitGroup{}
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			// Split the samples as evenly as possible.
+			n := sc.Sampling.Samples / int64(nWorkers)
+			if int64(i) < sc.Sampling.Samples%int64(nWorkers) {
+				n++
+			}
+
+			shard := sum.Shard()
+			walk(ctx, fractal, frame, rng.New(sc.Sampling.Seed, i), n, shard, done)
+			shard.Flush(nil)
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+	stopProgress(ctx, done)
+	buffer := sum.Buffer()
+
+	err = sc.WriteRaw(buffer)
+	if err != nil {
+		return err
+	}
+
+	counts := buffer.Channel(0)
+	toneMapper.Apply(counts)
+
+	img := palette.Render(pal, counts, width, height)
+
+	return sc.Output.WritePNG(img)
+}
+
+// walkChunk is the number of points walk plots between checks for cancellation.
+const walkChunk = 100000
+
+// walk plots n points of a random walk over fractal, or fewer if ctx is cancelled.
+func walk(ctx context.Context, fractal *tree.Tree, frame viewport.Frame, r *rand.Rand, n int64, out *accum.Shard, done *progress.Reporter) {
+	curNode := fractal
+	offset := geometry.XY{X: 0.0, Y: 0.0}
+	angle := 0.0
+	scale := 1.0
+
+	for p := int64(0); p < n; p++ {
+		if p%walkChunk == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			done.Add(min(walkChunk, n-p))
+			if out.Full() {
+				out.Flush(nil)
+			}
+		}
+
+		xy := curNode.RandomPoint(r)
+
+		xy = Rescale(xy, scale, angle, offset)
+
+		// Points outside the view are still part of the walk, they just aren't drawn.
+		px, py := frame.ToPixel(xy.X, xy.Y)
+		accum.Nearest{}.Splat(out, px, py, 0, 1.0)
+
+		nextNode := curNode.Continue(r)
+		switch nextNode {
+		case tree.None:
+			scale = 1.0
+			angle = 0.0
+			offset = geometry.XY{X: 0.0, Y: 0.0}
+			curNode = fractal
+		case tree.Left:
+			dOffset := curNode.LeftOrigin()
+			dOffset = Rescale(dOffset, scale, angle, offset)
+			offset.X = dOffset.X
+			offset.Y = dOffset.Y
+
+			angle += curNode.LeftAngle
+			scale *= curNode.LeftP
+			curNode = curNode.Left
+		case tree.Right:
+			dOffset := curNode.RightOrigin()
+			dOffset = Rescale(dOffset, scale, angle, offset)
+			offset.X = dOffset.X
+			offset.Y = dOffset.Y
+
+			angle -= curNode.RightAngle
+			scale *= 1.0 - curNode.LeftP
+			curNode = curNode.Right
+		}
+	}
+}
+
+func Rescale(xy geometry.XY, scale float64, angle float64, offset geometry.XY) geometry.XY {
+	x := xy.X * scale
+	y := xy.Y * scale
+
+	x2 := x*math.Cos(angle) - y*math.Sin(angle) + offset.X
+	y2 := x*math.Sin(angle) + y*math.Cos(angle) + offset.Y
+
+	return geometry.XY{X: x2, Y: y2}
+}
@@ -0,0 +1,58 @@
+package accum
+
+// A Grid is a grid of pixels that samples can be added to: a Buffer, or a Shard of a Sum.
+type Grid interface {
+	// Size returns the width and height of the grid in pixels.
+	Size() (int, int)
+
+	// Add adds weight to the pixel (x, y) of channel. Pixels outside the grid are ignored.
+	Add(x, y, channel int, weight float64)
+}
+
+// A Buffer accumulates weighted samples into a grid of pixels with one or more channels.
+//
+// A Buffer is not safe for concurrent use. Concurrent workers instead accumulate into
+// their own Shards of a Sum.
+type Buffer struct {
+	// Width and Height are the dimensions of the grid in pixels.
+	Width, Height int
+
+	// Channels is the number of independent values accumulated per pixel.
+	Channels int
+
+	// Data holds the accumulated values one channel after another, each channel in row-major order.
+	Data []float64
+}
+
+var _ Grid = (*Buffer)(nil)
+
+// New returns an empty Buffer.
+func New(width, height, channels int) *Buffer {
+	return &Buffer{
+		Width:    width,
+		Height:   height,
+		Channels: channels,
+		Data:     make([]float64, width*height*channels),
+	}
+}
+
+// Size returns the width and height of the grid in pixels.
+func (b *Buffer) Size() (int, int) {
+	return b.Width, b.Height
+}
+
+// Add adds weight to the pixel (x, y) of channel. Pixels outside the grid are ignored.
+func (b *Buffer) Add(x, y, channel int, weight float64) {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return
+	}
+
+	b.Data[(channel*b.Height+y)*b.Width+x] += weight
+}
+
+// Channel returns the values of a single channel in row-major order.
+// The returned slice shares memory with b.
+func (b *Buffer) Channel(channel int) []float64 {
+	size := b.Width * b.Height
+	return b.Data[channel*size : (channel+1)*size]
+}
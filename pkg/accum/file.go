@@ -0,0 +1,130 @@
+package accum
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The raw buffer file format stores a Buffer along with a header describing how it was rendered.
+// All integers and floats are little-endian.
+//
+//	offset  size      field
+//	0       8         magic, the ASCII bytes "DFRCTBUF"
+//	8       4         format version, uint32, currently 1
+//	12      4         header length H, uint32
+//	16      H         header, opaque to this package; diffractal writes the render's scene as JSON
+//	16+H    4         width W, uint32
+//	20+H    4         height Ht, uint32
+//	24+H    4         channels C, uint32
+//	28+H    8*W*Ht*C  values, float64, in the order of Buffer.Data
+const (
+	fileMagic   = "DFRCTBUF"
+	fileVersion = 1
+)
+
+// Limits on what Read accepts, so that a corrupt file can't exhaust memory.
+const (
+	// maxHeaderLength bounds the header, which is a short description of a render.
+	maxHeaderLength = 1 << 24
+
+	// maxValues bounds the number of values, at 32 GiB of float64s.
+	maxValues = 1 << 32
+
+	// readChunk is the number of values read at a time. The buffer grows as values
+	// arrive, so a file claiming more values than it holds fails before they are allocated.
+	readChunk = 1 << 16
+)
+
+// ErrNotBuffer is returned when reading data which is not a raw buffer file.
+var ErrNotBuffer = errors.New("not a raw buffer file")
+
+// Write writes b and header in the raw buffer file format.
+func (b *Buffer) Write(w io.Writer, header []byte) error {
+	bw := bufio.NewWriter(w)
+
+	_, err := bw.WriteString(fileMagic)
+	if err != nil {
+		return err
+	}
+
+	fields := []uint32{fileVersion, uint32(len(header))}
+	err = binary.Write(bw, binary.LittleEndian, fields)
+	if err != nil {
+		return err
+	}
+
+	_, err = bw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	dims := []uint32{uint32(b.Width), uint32(b.Height), uint32(b.Channels)}
+	err = binary.Write(bw, binary.LittleEndian, dims)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(bw, binary.LittleEndian, b.Data)
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Read reads a Buffer and its header from the raw buffer file format.
+func Read(r io.Reader) (*Buffer, []byte, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(fileMagic))
+	_, err := io.ReadFull(br, magic)
+	if err != nil || string(magic) != fileMagic {
+		return nil, nil, ErrNotBuffer
+	}
+
+	var fields [2]uint32
+	err = binary.Read(br, binary.LittleEndian, &fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading version: %w", err)
+	}
+	if fields[0] != fileVersion {
+		return nil, nil, fmt.Errorf("unsupported raw buffer version %d, want %d", fields[0], fileVersion)
+	}
+
+	if fields[1] > maxHeaderLength {
+		return nil, nil, fmt.Errorf("header length %d exceeds %d", fields[1], maxHeaderLength)
+	}
+
+	header := make([]byte, fields[1])
+	_, err = io.ReadFull(br, header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	var dims [3]uint32
+	err = binary.Read(br, binary.LittleEndian, &dims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading dimensions: %w", err)
+	}
+
+	n := float64(dims[0]) * float64(dims[1]) * float64(dims[2])
+	if n == 0 || n > maxValues {
+		return nil, nil, fmt.Errorf("dimensions %dx%dx%d out of range", dims[0], dims[1], dims[2])
+	}
+
+	b := &Buffer{Width: int(dims[0]), Height: int(dims[1]), Channels: int(dims[2])}
+	chunk := make([]float64, readChunk)
+	for remaining := int(n); remaining > 0; remaining -= len(chunk) {
+		chunk = chunk[:min(remaining, readChunk)]
+		err = binary.Read(br, binary.LittleEndian, chunk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %dx%dx%d values: %w", dims[0], dims[1], dims[2], err)
+		}
+		b.Data = append(b.Data, chunk...)
+	}
+
+	return b, header, nil
+}
@@ -0,0 +1,78 @@
+package accum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	b := New(3, 2, 2)
+	for i := range b.Data {
+		b.Data[i] = float64(i) * 0.5
+	}
+
+	var file bytes.Buffer
+	err := b.Write(&file, []byte(`{"kind":"test"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, header, err := Read(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(header) != `{"kind":"test"}` {
+		t.Errorf("got header %q", header)
+	}
+	if got.Width != 3 || got.Height != 2 || got.Channels != 2 {
+		t.Errorf("got dimensions %dx%dx%d, want 3x2x2", got.Width, got.Height, got.Channels)
+	}
+	if !slices.Equal(got.Data, b.Data) {
+		t.Errorf("got values %v, want %v", got.Data, b.Data)
+	}
+}
+
+// rawFile returns a raw buffer file with the given dimensions, header length, and values.
+func rawFile(headerLength uint32, dims [3]uint32, values int) []byte {
+	var file bytes.Buffer
+	file.WriteString(fileMagic)
+	_ = binary.Write(&file, binary.LittleEndian, []uint32{fileVersion, headerLength})
+	file.Write(make([]byte, headerLength))
+	_ = binary.Write(&file, binary.LittleEndian, dims)
+	_ = binary.Write(&file, binary.LittleEndian, make([]float64, values))
+	return file.Bytes()
+}
+
+func TestRead_Corrupt(t *testing.T) {
+	tcs := []struct {
+		name string
+		file []byte
+	}{
+		{name: "empty", file: nil},
+		{name: "not a buffer", file: []byte("PNG and other things")},
+		{name: "truncated header", file: rawFile(10, [3]uint32{1, 1, 1}, 1)[:20]},
+		{name: "huge header", file: binary.LittleEndian.AppendUint32([]byte(fileMagic+"\x01\x00\x00\x00"), 1<<31)},
+		{name: "truncated values", file: rawFile(0, [3]uint32{4, 4, 1}, 15)},
+		{name: "zero dimension", file: rawFile(0, [3]uint32{0, 4, 1}, 0)},
+		{name: "huge dimensions", file: rawFile(0, [3]uint32{1 << 31, 1 << 31, 4}, 1)},
+		{name: "many values missing", file: rawFile(0, [3]uint32{1 << 15, 1 << 15, 4}, 100)},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := Read(bytes.NewReader(tc.file))
+			if err == nil {
+				t.Fatalf("got %dx%dx%d buffer, want error", got.Width, got.Height, got.Channels)
+			}
+		})
+	}
+
+	_, _, err := Read(bytes.NewReader([]byte("PNG and other things")))
+	if !errors.Is(err, ErrNotBuffer) {
+		t.Errorf("got %v, want ErrNotBuffer", err)
+	}
+}
@@ -0,0 +1,112 @@
+package accum
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Splatter distributes a sample over the pixels near it.
+//
+// Sample positions are continuous pixel coordinates: pixel (i, j) covers [i, i+1) x [j, j+1).
+type Splatter interface {
+	Splat(b Grid, px, py float64, channel int, weight float64)
+}
+
+// Nearest adds the whole sample to the pixel containing it.
+type Nearest struct{}
+
+var _ Splatter = Nearest{}
+
+func (Nearest) Splat(b Grid, px, py float64, channel int, weight float64) {
+	b.Add(int(math.Floor(px)), int(math.Floor(py)), channel, weight)
+}
+
+// Bilinear divides the sample between the four pixels whose centers surround it.
+type Bilinear struct{}
+
+var _ Splatter = Bilinear{}
+
+func (Bilinear) Splat(b Grid, px, py float64, channel int, weight float64) {
+	// Shift so that pixel centers lie on integer coordinates.
+	px -= 0.5
+	py -= 0.5
+
+	fx, fy := math.Floor(px), math.Floor(py)
+	x, y := int(fx), int(fy)
+	dx, dy := px-fx, py-fy
+
+	b.Add(x, y, channel, weight*(1.0-dx)*(1.0-dy))
+	b.Add(x+1, y, channel, weight*dx*(1.0-dy))
+	b.Add(x, y+1, channel, weight*(1.0-dx)*dy)
+	b.Add(x+1, y+1, channel, weight*dx*dy)
+}
+
+// Gaussian spreads the sample over nearby pixels with a normalized Gaussian kernel.
+type Gaussian struct {
+	// Sigma is the standard deviation of the kernel in pixels.
+	Sigma float64
+}
+
+var _ Splatter = Gaussian{}
+
+func (g Gaussian) Splat(b Grid, px, py float64, channel int, weight float64) {
+	px -= 0.5
+	py -= 0.5
+
+	radius := int(math.Ceil(3.0 * g.Sigma))
+	cx, cy := int(math.Round(px)), int(math.Round(py))
+	width, height := b.Size()
+	if cx < -radius || cy < -radius || cx >= width+radius || cy >= height+radius {
+		return
+	}
+
+	inv2S2 := 1.0 / (2.0 * g.Sigma * g.Sigma)
+
+	// Normalize over the truncated kernel so splatting preserves total weight.
+	total := 0.0
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			total += gaussian(float64(x)-px, float64(y)-py, inv2S2)
+		}
+	}
+
+	scale := weight / total
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			b.Add(x, y, channel, gaussian(float64(x)-px, float64(y)-py, inv2S2)*scale)
+		}
+	}
+}
+
+func gaussian(dx, dy, inv2S2 float64) float64 {
+	return math.Exp(-(dx*dx + dy*dy) * inv2S2)
+}
+
+// ParseSplatter parses a Splatter from its name: "nearest", "bilinear", or "gaussian:SIGMA".
+func ParseSplatter(s string) (Splatter, error) {
+	name, arg, hasArg := strings.Cut(s, ":")
+
+	switch name {
+	case "nearest":
+		return Nearest{}, nil
+	case "bilinear":
+		return Bilinear{}, nil
+	case "gaussian":
+		sigma := 0.5
+		if hasArg {
+			var err error
+			sigma, err = strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing gaussian sigma %q: %w", arg, err)
+			}
+		}
+		if sigma <= 0.0 {
+			return nil, fmt.Errorf("gaussian sigma must be positive, got %v", sigma)
+		}
+		return Gaussian{Sigma: sigma}, nil
+	default:
+		return nil, fmt.Errorf("unknown splat %q", s)
+	}
+}
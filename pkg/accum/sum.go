@@ -0,0 +1,269 @@
+package accum
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// A Sum adds up the samples of concurrent workers, each of which accumulates into
+// its own Shard and flushes it into the Sum from time to time.
+//
+// Values are summed exactly, in 64.64 fixed point, so the total doesn't depend on the
+// order samples are added in or when shards are flushed: a render comes out the same
+// however its workers are scheduled, and whether or not it was interrupted and resumed.
+type Sum struct {
+	// Width and Height are the dimensions of the grid in pixels.
+	Width, Height int
+
+	// Channels is the number of independent values accumulated per pixel.
+	Channels int
+
+	mu sync.Mutex
+
+	// values are laid out as Buffer.Data.
+	values []fixed
+}
+
+// NewSum returns an empty Sum.
+func NewSum(width, height, channels int) *Sum {
+	return &Sum{
+		Width:    width,
+		Height:   height,
+		Channels: channels,
+		values:   make([]fixed, width*height*channels),
+	}
+}
+
+// fixed is the 64.64 fixed point number hi + lo/2^64.
+type fixed struct {
+	hi int64
+	lo uint64
+}
+
+// toFixed returns v in fixed point, dropping bits below 2^-64.
+// V must be less than 2^63 in magnitude.
+func toFixed(v float64) fixed {
+	b := math.Float64bits(v)
+
+	exponent := int(b >> 52 & 0x7ff)
+	if exponent == 0 {
+		// Zero, or subnormal and so far below 2^-64.
+		return fixed{}
+	}
+
+	// |v| is mantissa * 2^(exponent-1075), and fixed point scales it by 2^64.
+	mantissa := b&(1<<52-1) | 1<<52
+	var f fixed
+	switch shift := exponent - 1075 + 64; {
+	case shift >= 64:
+		f.hi = int64(mantissa << (shift - 64))
+	case shift >= 0:
+		f.hi, f.lo = int64(mantissa>>(64-shift)), mantissa<<shift
+	case shift > -64:
+		f.lo = mantissa >> -shift
+	}
+
+	if v < 0.0 {
+		f = f.negate()
+	}
+
+	return f
+}
+
+func (f fixed) negate() fixed {
+	lo, borrow := bits.Sub64(0, f.lo, 0)
+	return fixed{hi: -f.hi - int64(borrow), lo: lo}
+}
+
+func (f *fixed) add(g fixed) {
+	var carry uint64
+	f.lo, carry = bits.Add64(f.lo, g.lo, 0)
+	f.hi += g.hi + int64(carry)
+}
+
+func (f fixed) float64() float64 {
+	// Convert the magnitude, so that rounding lo can't carry into hi.
+	if f.hi < 0 {
+		return -f.negate().float64()
+	}
+
+	return float64(f.hi) + math.Ldexp(float64(f.lo), -64)
+}
+
+// Buffer returns the sums rounded to float64s.
+func (s *Sum) Buffer() *Buffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := New(s.Width, s.Height, s.Channels)
+	for i, v := range s.values {
+		b.Data[i] = v.float64()
+	}
+
+	return b
+}
+
+// Snapshot encodes s and calls save with the encoding. No shard flushes until save
+// returns, so progress recorded by Flush is consistent with the encoded sums.
+func (s *Sum) Snapshot(save func(data []byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]byte, 0, 16*len(s.values))
+	for _, v := range s.values {
+		data = binary.LittleEndian.AppendUint64(data, uint64(v.hi))
+		data = binary.LittleEndian.AppendUint64(data, v.lo)
+	}
+
+	return save(data)
+}
+
+// Restore replaces the contents of s with a Snapshot of a Sum of the same dimensions.
+func (s *Sum) Restore(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(data) != 16*len(s.values) {
+		return fmt.Errorf("snapshot has %d bytes, want %d", len(data), 16*len(s.values))
+	}
+
+	for i := range s.values {
+		s.values[i] = fixed{
+			hi: int64(binary.LittleEndian.Uint64(data[16*i:])),
+			lo: binary.LittleEndian.Uint64(data[16*i+8:]),
+		}
+	}
+
+	return nil
+}
+
+// tileBits is the log2 of the side of the square tiles a Shard allocates.
+const tileBits = 4
+
+const tileSide = 1 << tileBits
+
+// shardLimit is the number of values a Shard holds before it reports being Full,
+// taking 16 MiB.
+const shardLimit = 1 << 20
+
+// tile is a square of pixels of one channel, in row-major order.
+type tile [tileSide * tileSide]fixed
+
+// A Shard accumulates one worker's samples for a Sum. It allocates tiles of the grid
+// only as samples land in them, and Flush empties it, so a worker which flushes its
+// Shard whenever it is Full needs memory for about shardLimit values however large
+// the grid is.
+//
+// A Shard is not safe for concurrent use.
+type Shard struct {
+	sum *Sum
+
+	// tilesX and tilesY are the number of tiles across and down a channel.
+	tilesX, tilesY int
+
+	// tiles are the tiles of every channel, one channel after another, each in
+	// row-major order. Tiles no sample has landed in since the last Flush are nil.
+	tiles []*tile
+
+	// used are the indices of the allocated tiles.
+	used []int
+
+	// free are emptied tiles to reuse.
+	free []*tile
+}
+
+var _ Grid = (*Shard)(nil)
+
+// Shard returns an empty Shard of s.
+func (s *Sum) Shard() *Shard {
+	tilesX := (s.Width + tileSide - 1) >> tileBits
+	tilesY := (s.Height + tileSide - 1) >> tileBits
+
+	return &Shard{
+		sum:    s,
+		tilesX: tilesX,
+		tilesY: tilesY,
+		tiles:  make([]*tile, tilesX*tilesY*s.Channels),
+	}
+}
+
+// Size returns the width and height of the grid in pixels.
+func (s *Shard) Size() (int, int) {
+	return s.sum.Width, s.sum.Height
+}
+
+// Add adds weight to the pixel (x, y) of channel. Pixels outside the grid are ignored.
+func (s *Shard) Add(x, y, channel int, weight float64) {
+	if x < 0 || y < 0 || x >= s.sum.Width || y >= s.sum.Height {
+		return
+	}
+
+	i := (channel*s.tilesY+y>>tileBits)*s.tilesX + x>>tileBits
+	t := s.tiles[i]
+	if t == nil {
+		t = s.allocate(i)
+	}
+
+	t[(y&(tileSide-1))<<tileBits+x&(tileSide-1)].add(toFixed(weight))
+}
+
+// allocate returns an empty tile to hold tile i.
+func (s *Shard) allocate(i int) *tile {
+	var t *tile
+	if n := len(s.free); n > 0 {
+		t, s.free = s.free[n-1], s.free[:n-1]
+	} else {
+		t = &tile{}
+	}
+
+	s.tiles[i] = t
+	s.used = append(s.used, i)
+
+	return t
+}
+
+// Full reports whether s holds at least shardLimit values, and should be flushed.
+func (s *Shard) Full() bool {
+	return len(s.used)*tileSide*tileSide >= shardLimit
+}
+
+// Flush adds the samples of s to its Sum and empties s.
+//
+// Then, with no other shard able to flush or the Sum to be snapshotted, it calls
+// record, if not nil. Workers record there how far their work has got, so that a
+// Snapshot holds exactly the samples of the work recorded.
+func (s *Shard) Flush(record func()) {
+	sum := s.sum
+	sum.mu.Lock()
+	defer sum.mu.Unlock()
+
+	for _, i := range s.used {
+		t := s.tiles[i]
+
+		channel := i / (s.tilesX * s.tilesY)
+		tx, ty := i%s.tilesX<<tileBits, i/s.tilesX%s.tilesY<<tileBits
+
+		for j, v := range t {
+			if v == (fixed{}) {
+				continue
+			}
+
+			// Tiles on the right and bottom edges overhang the grid, but samples
+			// never land in the overhang.
+			x, y := tx+j&(tileSide-1), ty+j>>tileBits
+			sum.values[(channel*sum.Height+y)*sum.Width+x].add(v)
+		}
+
+		*t = tile{}
+		s.tiles[i] = nil
+		s.free = append(s.free, t)
+	}
+	s.used = s.used[:0]
+
+	if record != nil {
+		record()
+	}
+}
@@ -0,0 +1,190 @@
+package accum
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestToFixed(t *testing.T) {
+	tcs := []float64{0, 1, -1, 0.5, -0.75, 0.1, 1.0 / 3.0, 1e-19, -1e-19, 12345.678, -98765.4321, 1e18, math.SmallestNonzeroFloat64}
+
+	for _, v := range tcs {
+		got := toFixed(v).float64()
+		// Only bits below 2^-64 are lost.
+		if math.Abs(got-v) > 0x1p-64 {
+			t.Errorf("toFixed(%v) = %v", v, got)
+		}
+	}
+}
+
+func TestFixed_Add(t *testing.T) {
+	tcs := []struct {
+		values []float64
+		want   float64
+	}{
+		{values: []float64{0.5, 0.25}, want: 0.75},
+		{values: []float64{0.75, 0.75}, want: 1.5},
+		{values: []float64{1, -0.25}, want: 0.75},
+		{values: []float64{-0.5, -0.75}, want: -1.25},
+		// Exactly, where adding float64s gives 0x1p-54.
+		{values: []float64{0.1, 0.2, -0.3}, want: 0x1p-55},
+		{values: []float64{1e15, 0.125, -1e15}, want: 0.125},
+	}
+
+	for _, tc := range tcs {
+		var f fixed
+		for _, v := range tc.values {
+			f.add(toFixed(v))
+		}
+
+		if got := f.float64(); got != tc.want {
+			t.Errorf("sum of %v = %v, want %v", tc.values, got, tc.want)
+		}
+	}
+}
+
+// TestSum_Order checks that however samples are split between shards, and whenever
+// the shards are flushed, the Sum comes out the same.
+func TestSum_Order(t *testing.T) {
+	type sample struct {
+		x, y, channel int
+		weight        float64
+	}
+
+	r := rand.New(rand.NewSource(1))
+	samples := make([]sample, 10000)
+	for i := range samples {
+		samples[i] = sample{x: r.Intn(5), y: r.Intn(3), channel: r.Intn(2), weight: r.ExpFloat64() * math.Pow(10, float64(r.Intn(12)-6))}
+	}
+
+	var want []float64
+	for try := 0; try < 10; try++ {
+		sum := NewSum(5, 3, 2)
+		shards := []*Shard{sum.Shard(), sum.Shard(), sum.Shard()}
+
+		for _, i := range r.Perm(len(samples)) {
+			s := samples[i]
+			shard := shards[r.Intn(len(shards))]
+			shard.Add(s.x, s.y, s.channel, s.weight)
+
+			if r.Intn(100) == 0 {
+				shard.Flush(nil)
+			}
+		}
+		for _, shard := range shards {
+			shard.Flush(nil)
+		}
+
+		got := sum.Buffer().Data
+		if want == nil {
+			want = got
+		} else if !slices.Equal(got, want) {
+			t.Fatalf("try %d summed to %v, want %v", try, got, want)
+		}
+	}
+}
+
+func TestShard_Flush(t *testing.T) {
+	// Neither dimension is a whole number of tiles.
+	sum := NewSum(37, 20, 2)
+	shard := sum.Shard()
+	if w, h := shard.Size(); w != 37 || h != 20 {
+		t.Errorf("got size %dx%d, want 37x20", w, h)
+	}
+
+	want := New(37, 20, 2)
+	for c := 0; c < 2; c++ {
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 37; x++ {
+				weight := float64(1 + x + 100*y + 10000*c)
+				shard.Add(x, y, c, weight)
+				want.Add(x, y, c, weight)
+			}
+		}
+	}
+
+	// Samples outside the grid are dropped.
+	shard.Add(-1, 0, 0, 1)
+	shard.Add(37, 0, 0, 1)
+	shard.Add(0, 20, 1, 1)
+
+	recorded := false
+	shard.Flush(func() { recorded = true })
+	if !recorded {
+		t.Error("Flush didn't call record")
+	}
+
+	if got := sum.Buffer(); !slices.Equal(got.Data, want.Data) {
+		t.Errorf("got %v, want %v", got.Data, want.Data)
+	}
+
+	// Flushing empties the shard.
+	shard.Flush(nil)
+	if got := sum.Buffer(); !slices.Equal(got.Data, want.Data) {
+		t.Errorf("flushing twice gave %v, want %v", got.Data, want.Data)
+	}
+}
+
+func TestShard_Full(t *testing.T) {
+	sum := NewSum(4096, 4096, 1)
+	shard := sum.Shard()
+
+	// Each sample lands in a tile of its own.
+	tiles := 0
+	for y := 0; y < 4096 && !shard.Full(); y += tileSide {
+		for x := 0; x < 4096 && !shard.Full(); x += tileSide {
+			shard.Add(x, y, 0, 1)
+			tiles++
+		}
+	}
+
+	if want := shardLimit / (tileSide * tileSide); tiles != want {
+		t.Errorf("got Full after %d tiles, want %d", tiles, want)
+	}
+
+	shard.Flush(nil)
+	if shard.Full() {
+		t.Error("got Full after Flush")
+	}
+}
+
+func TestSum_Snapshot(t *testing.T) {
+	sum := NewSum(3, 2, 1)
+	shard := sum.Shard()
+	shard.Add(0, 0, 0, 0.1)
+	shard.Add(2, 1, 0, -7.25)
+	shard.Add(1, 1, 0, 1e12)
+	shard.Flush(nil)
+
+	var data []byte
+	err := sum.Snapshot(func(d []byte) error {
+		data = d
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewSum(3, 2, 1)
+	err = restored.Restore(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restored sums carry on exactly as the original would.
+	for _, s := range []*Sum{sum, restored} {
+		shard := s.Shard()
+		shard.Add(0, 0, 0, 0.2)
+		shard.Flush(nil)
+	}
+	if got, want := restored.Buffer().Data, sum.Buffer().Data; !slices.Equal(got, want) {
+		t.Errorf("restored sum gave %v, want %v", got, want)
+	}
+
+	err = NewSum(2, 2, 1).Restore(data)
+	if err == nil {
+		t.Error("restored a snapshot of a different size")
+	}
+}
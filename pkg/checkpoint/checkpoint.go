@@ -0,0 +1,119 @@
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SceneFile is the name of the file in a checkpoint directory which records the
+// render the checkpoint belongs to.
+const SceneFile = "scene.json"
+
+// StateFile is the name of the file in a checkpoint directory which holds the state
+// of the render.
+const StateFile = "state.gob"
+
+// A Store saves the state of a render to a directory, so that an interrupted render
+// can be resumed.
+//
+// The methods of a nil Store do nothing, so callers need not check whether
+// checkpointing is enabled.
+type Store struct {
+	dir string
+}
+
+// Open prepares dir to hold checkpoints of the render described by header.
+//
+// If resume is true, dir must already hold checkpoints of a render with an identical
+// header. Otherwise any checkpoints in dir are discarded.
+func Open(dir string, header []byte, resume bool) (*Store, error) {
+	s := &Store{dir: dir}
+	path := filepath.Join(dir, SceneFile)
+
+	if resume {
+		saved, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(saved, header) {
+			return nil, fmt.Errorf("checkpoint in %s is of a different render; it can only be resumed with the same scene and worker count", dir)
+		}
+		return s, nil
+	}
+
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Remove(s.path())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	err = writeAtomic(path, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) path() string {
+	return filepath.Join(s.dir, StateFile)
+}
+
+// Save records the state of the render, replacing the previous checkpoint.
+//
+// The checkpoint is written to a temporary file and renamed into place, so an
+// interruption never leaves a partially written checkpoint.
+func (s *Store) Save(state any) error {
+	if s == nil {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(state)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+
+	return writeAtomic(s.path(), buf.Bytes())
+}
+
+// Load reads the last checkpoint into state.
+// Returns false if there is no checkpoint.
+func (s *Store) Load(state any) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(state)
+	if err != nil {
+		return false, fmt.Errorf("decoding checkpoint: %w", err)
+	}
+
+	return true, nil
+}
+
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	err := os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
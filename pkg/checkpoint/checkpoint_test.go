@@ -0,0 +1,188 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testState struct {
+	Rows []int
+	Sum  []byte
+}
+
+func TestStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	header := []byte(`{"version": 1}`)
+
+	s, err := Open(dir, header, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got testState
+	ok, err := s.Load(&got)
+	if err != nil || ok {
+		t.Fatalf("Load of a new checkpoint = %t, %v; want false, nil", ok, err)
+	}
+
+	for _, want := range []testState{
+		{Rows: []int{3, 4}, Sum: []byte{1, 2, 3}},
+		// Saving replaces the earlier state.
+		{Rows: []int{5, 6}, Sum: []byte{4}},
+	} {
+		err = s.Save(&want)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Resume as a new process would.
+		resumed, err := Open(dir, header, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got = testState{}
+		ok, err = resumed.Load(&got)
+		if err != nil || !ok {
+			t.Fatalf("Load = %t, %v; want true, nil", ok, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+
+	// Writes go through a temporary file which is renamed into place.
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("got temporary files %v left behind", matches)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	header := []byte(`{"version": 1, "seed": 1}`)
+
+	tcs := []struct {
+		name    string
+		header  []byte
+		resume  bool
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "resume", header: header, resume: true, wantOK: true},
+		{name: "different render", header: []byte(`{"version": 1, "seed": 2}`), resume: true, wantErr: true},
+		// Starting over discards the saved state, whatever the render.
+		{name: "start over", header: header},
+		{name: "start another", header: []byte(`{"version": 1, "seed": 2}`)},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			s, err := Open(dir, header, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Save(&testState{Rows: []int{1}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s, err = Open(dir, tc.header, tc.resume)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("got nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var state testState
+			ok, err := s.Load(&state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.wantOK {
+				t.Errorf("got saved state %t, want %t", ok, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestOpen_ResumeMissing(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "none"), []byte("{}"), true)
+	if err == nil {
+		t.Error("resumed a checkpoint which doesn't exist")
+	}
+}
+
+func TestStore_InterruptedSave(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, []byte("{}"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := testState{Rows: []int{7}}
+	err = s.Save(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A save interrupted before its rename leaves only a partial temporary file.
+	err = os.WriteFile(filepath.Join(dir, StateFile+".tmp"), []byte("partial"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got testState
+	ok, err := s.Load(&got)
+	if err != nil || !ok {
+		t.Fatalf("Load = %t, %v; want true, nil", ok, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestStore_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, []byte("{}"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, StateFile), []byte("not gob"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state testState
+	_, err = s.Load(&state)
+	if err == nil {
+		t.Error("loaded a corrupt checkpoint")
+	}
+}
+
+func TestStore_Nil(t *testing.T) {
+	var s *Store
+
+	err := s.Save(&testState{})
+	if err != nil {
+		t.Errorf("Save: %v", err)
+	}
+
+	ok, err := s.Load(&testState{})
+	if ok || err != nil {
+		t.Errorf("Load = %t, %v; want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,184 @@
+package escapetime
+
+import (
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// BailoutUsage describes the syntax accepted by ParseBailout.
+const BailoutUsage = `when orbits count as escaped: radius:R (|z| reaches R), ` +
+	`norm:R (as radius, comparing squared magnitudes to save a square root), ` +
+	`real:W or imag:W (the real or imaginary part of z leaves the strip of half-width W), ` +
+	`converge:EPSILON (z moves less than EPSILON in a step, for maps whose orbits converge rather than escape), ` +
+	`or attractor:EPSILON:X:Y[:X:Y...] (z comes within EPSILON of a point X+iY of a finite attractor, ` +
+	`such as a root found by Newton's method); empty means the largest radius the map can take`
+
+// A Bailout decides when an orbit has escaped, ending its iteration.
+type Bailout interface {
+	// Escaped reports whether the orbit has escaped on reaching z by a step of
+	// step, the difference from the previous point. Step is infinite for the
+	// starting point of the orbit.
+	Escaped(z, step complex128) bool
+}
+
+// A Radial Bailout is one orbits escape by leaving a disk around the origin,
+// which SmoothCount and Distance need.
+type Radial interface {
+	Bailout
+
+	// Radius returns the radius of the disk.
+	Radius() float64
+}
+
+var (
+	_ Radial  = Radius{}
+	_ Radial  = Norm{}
+	_ Bailout = Strip{}
+	_ Bailout = Converge{}
+	_ Bailout = Attractor{}
+)
+
+// Radius is escaped once |z| reaches R.
+type Radius struct {
+	R float64
+}
+
+func (b Radius) Escaped(z, _ complex128) bool {
+	// Orbits which have overflowed to NaN count as escaped too.
+	return !(cmplx.Abs(z) < b.R)
+}
+
+func (b Radius) Radius() float64 {
+	return b.R
+}
+
+// Norm is Radius, comparing the squared magnitude of z to R squared
+// so that it needs no square root.
+type Norm struct {
+	R float64
+}
+
+func (b Norm) Escaped(z, _ complex128) bool {
+	return !(real(z)*real(z)+imag(z)*imag(z) < b.R*b.R)
+}
+
+func (b Norm) Radius() float64 {
+	return b.R
+}
+
+// Strip is escaped once the real part of z, or the imaginary part if Imaginary is
+// set, is at least Width from zero.
+type Strip struct {
+	Width     float64
+	Imaginary bool
+}
+
+func (b Strip) Escaped(z, _ complex128) bool {
+	if b.Imaginary {
+		return math.Abs(imag(z)) >= b.Width
+	}
+	return math.Abs(real(z)) >= b.Width
+}
+
+// Converge is escaped once the orbit moves less than Epsilon in a step, for
+// families whose orbits settle down rather than run off to infinity.
+type Converge struct {
+	Epsilon float64
+}
+
+func (b Converge) Escaped(_, step complex128) bool {
+	return cmplx.Abs(step) < b.Epsilon
+}
+
+// Attractor is escaped once z comes within Epsilon of any of Points, such as the
+// roots that Newton's method converges to.
+type Attractor struct {
+	Points  []complex128
+	Epsilon float64
+}
+
+func (b Attractor) Escaped(z, _ complex128) bool {
+	for _, p := range b.Points {
+		if cmplx.Abs(z-p) < b.Epsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxRadius returns a bailout radius for m, as large as it can be without a step of m
+// overflowing for points inside it, so that SmoothCount is as accurate as possible.
+func MaxRadius(m transforms.ComplexMap) float64 {
+	degree := 1.0
+	for _, step := range transforms.Steps(m) {
+		degree = math.Max(degree, step.Degree())
+	}
+
+	// Leave a factor of two in the exponent for the constant terms of the steps.
+	return math.Pow(math.MaxFloat64, 1.0/(2.0*degree))
+}
+
+// ParseBailout parses a Bailout for orbits of m, such as "radius:10" or
+// "attractor:1e-6:1:0:-0.5:0.866:-0.5:-0.866". Empty means Radius{MaxRadius(m)}.
+func ParseBailout(spec string, m transforms.ComplexMap) (Bailout, error) {
+	if spec == "" {
+		return Radius{R: MaxRadius(m)}, nil
+	}
+
+	fields := strings.Split(spec, ":")
+	name := fields[0]
+
+	switch name {
+	case "radius", "norm", "real", "imag", "converge", "attractor":
+	default:
+		return nil, fmt.Errorf("unknown bailout %q, want radius, norm, real, imag, converge, or attractor", name)
+	}
+
+	args := make([]float64, len(fields)-1)
+	for i, field := range fields[1:] {
+		arg, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing argument %q of %s bailout: %w", field, name, err)
+		}
+		args[i] = arg
+	}
+
+	if name == "attractor" {
+		if len(args) < 3 || len(args)%2 == 0 {
+			return nil, fmt.Errorf("attractor bailout takes an epsilon and pairs of coordinates, got %d numbers", len(args))
+		}
+		if args[0] <= 0.0 {
+			return nil, fmt.Errorf("attractor bailout epsilon must be positive, got %v", args[0])
+		}
+
+		result := Attractor{Epsilon: args[0]}
+		for i := 1; i < len(args); i += 2 {
+			result.Points = append(result.Points, complex(args[i], args[i+1]))
+		}
+		return result, nil
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s bailout takes 1 number, got %d", name, len(args))
+	}
+	if args[0] <= 0.0 {
+		return nil, fmt.Errorf("%s bailout must be positive, got %v", name, args[0])
+	}
+
+	switch name {
+	case "radius":
+		return Radius{R: args[0]}, nil
+	case "norm":
+		return Norm{R: args[0]}, nil
+	case "real":
+		return Strip{Width: args[0]}, nil
+	case "imag":
+		return Strip{Width: args[0], Imaginary: true}, nil
+	default:
+		return Converge{Epsilon: args[0]}, nil
+	}
+}
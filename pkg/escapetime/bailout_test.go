@@ -0,0 +1,66 @@
+package escapetime
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseBailout(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		want    Bailout
+		wantErr bool
+	}{
+		{spec: "", want: Radius{R: math.Pow(math.MaxFloat64, 0.25)}},
+		{spec: "radius:10", want: Radius{R: 10}},
+		{spec: "norm:4", want: Norm{R: 4}},
+		{spec: "real:2", want: Strip{Width: 2}},
+		{spec: "imag:2", want: Strip{Width: 2, Imaginary: true}},
+		{spec: "converge:1e-9", want: Converge{Epsilon: 1e-9}},
+		{spec: "attractor:0.01:1:0", want: Attractor{Epsilon: 0.01, Points: []complex128{1}}},
+		{spec: "attractor:0.01:1:0:-0.5:0.5", want: Attractor{Epsilon: 0.01, Points: []complex128{1, complex(-0.5, 0.5)}}},
+		{spec: "radius", wantErr: true},
+		{spec: "radius:10:20", wantErr: true},
+		{spec: "radius:-1", wantErr: true},
+		{spec: "radius:ten", wantErr: true},
+		{spec: "converge:0", wantErr: true},
+		{spec: "attractor:0.01", wantErr: true},
+		{spec: "attractor:0.01:1", wantErr: true},
+		{spec: "attractor:0:1:0", wantErr: true},
+		{spec: "circle:2", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		got, err := ParseBailout(tc.spec, transforms.Julia2{})
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseBailout(%q) = %v, want error", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseBailout(%q): %v", tc.spec, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseBailout(%q) = %#v, want %#v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestDistance_NonRadial(t *testing.T) {
+	o := Options{Bailout: Strip{Width: 2}, MaxIterations: 100, PixelSize: 0.01}
+
+	if err := CheckBailout(Distance{Width: 1}, o.Bailout); err == nil {
+		t.Error("CheckBailout accepted distance coloring with a strip bailout")
+	}
+
+	// Samples must not poison the tone map with NaN even so.
+	got := Distance{Width: 1}.Value(transforms.Julia2{C: -1}, 0, 0.1, o)
+	if got != 0.0 {
+		t.Errorf("got %v, want 0", got)
+	}
+}
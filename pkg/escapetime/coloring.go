@@ -0,0 +1,180 @@
+package escapetime
+
+import (
+	"errors"
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// ColoringUsage describes the syntax accepted by ParseColoring.
+const ColoringUsage = `what each sample measures: smooth (escape time), ` +
+	`distance[:WIDTH] (boundaries WIDTH pixels wide from the distance estimate), ` +
+	`period (the period plus the multiplier magnitude of the cycle interior orbits fall into), ` +
+	`or ` + trapUsage
+
+// Options are the settings every Coloring iterates orbits with.
+type Options struct {
+	Bailout       Bailout
+	MaxIterations int
+
+	// PixelSize is the size of a pixel in plane units.
+	PixelSize float64
+
+	// Tolerance is how close orbits must come to an earlier point to count as periodic,
+	// ending their iteration early. Zero disables cycle detection.
+	Tolerance float64
+}
+
+// A Coloring measures the orbit of a sample point.
+type Coloring interface {
+	// Value iterates m from z, starting with step start of m, and returns the value of the sample.
+	Value(m transforms.ComplexMap, start int, z complex128, o Options) float64
+}
+
+var (
+	_ Coloring = Smooth{}
+	_ Coloring = Distance{}
+	_ Coloring = Period{}
+	_ Coloring = Trapped{}
+)
+
+// Smooth is the smooth escape time of orbits, or zero for orbits which don't escape.
+// Escape times are only smooth for Radial bailouts, as EscapeCount says.
+type Smooth struct{}
+
+func (Smooth) Value(m transforms.ComplexMap, start int, z complex128, o Options) float64 {
+	orbit := Iterate(m, start, z, o.Bailout, o.MaxIterations, o.Tolerance)
+	if !orbit.Escaped {
+		return 0.0
+	}
+
+	return EscapeCount(m, start, orbit, o.Bailout)
+}
+
+// Period colors the interior of the set of orbits which don't escape by the cycles
+// they fall into. Samples are the period of the cycle plus the magnitude of its
+// multiplier, which is below one for the attracting cycles interiors are made of.
+//
+// Escaping orbits, and orbits not found to be periodic, are zero.
+// Needs a positive Options.Tolerance.
+type Period struct{}
+
+func (Period) Value(m transforms.ComplexMap, start int, z complex128, o Options) float64 {
+	return PeriodValue(Iterate(m, start, z, o.Bailout, o.MaxIterations, o.Tolerance))
+}
+
+// PeriodValue is the value Period gives orbit.
+func PeriodValue(orbit Orbit) float64 {
+	if orbit.Period == 0 {
+		return 0.0
+	}
+
+	v := float64(orbit.Period)
+	if r := cmplx.Abs(orbit.Multiplier); r < 1.0 {
+		v += r
+	}
+	return v
+}
+
+// Distance draws the boundary of the set of orbits which don't escape as lines of
+// constant width in pixels, whatever the zoom.
+//
+// Uses the exterior distance estimate |z| log|z| / |dz/dz0|, so the map must be
+// transforms.Differentiable, and the bailout Radial, as CheckBailout checks; samples
+// are zero otherwise. Samples are one inside the set, fall off linearly to zero at
+// Width pixels outside, and so anti-alias when averaged over a pixel.
+type Distance struct {
+	Width float64
+}
+
+func (d Distance) Value(m transforms.ComplexMap, start int, z complex128, o Options) float64 {
+	radial, ok := o.Bailout.(Radial)
+	if !ok {
+		return 0.0
+	}
+
+	// The derivative grows faster than the orbit, so a bailout beyond DistanceBailout
+	// risks it overflowing while gaining no accuracy.
+	bailout := Radius{R: math.Min(radial.Radius(), DistanceBailout)}
+
+	z, dz, n, ok := EscapeDerivative(m, start, z, bailout, o.MaxIterations)
+	if !ok {
+		return 0.0
+	}
+	if n >= o.MaxIterations {
+		return 1.0
+	}
+
+	return d.Estimate(z, dz, o.PixelSize)
+}
+
+// Estimate is the value of a sample whose orbit escaped to z with derivative dz,
+// in a view with pixels pixelSize across.
+func (d Distance) Estimate(z, dz complex128, pixelSize float64) float64 {
+	r := cmplx.Abs(z)
+	distance := r * math.Log(r) / cmplx.Abs(dz)
+	if math.IsNaN(distance) || math.IsInf(distance, 0) {
+		// The orbit or derivative overflowed anyway, so the estimate is lost.
+		return 0.0
+	}
+
+	return math.Max(0.0, 1.0-distance/(d.Width*pixelSize))
+}
+
+// CheckBailout returns an error if c can't measure orbits escaping bailout.
+func CheckBailout(c Coloring, bailout Bailout) error {
+	_, isDistance := c.(Distance)
+	_, isRadial := bailout.(Radial)
+	if isDistance && !isRadial {
+		return errors.New("distance coloring needs a radius or norm bailout")
+	}
+	return nil
+}
+
+// DistanceBailout is large enough for accurate distance estimates.
+const DistanceBailout = 1e10
+
+// ParseColoring parses a Coloring, such as "smooth", "distance:1.5", or
+// "trap:distance:0.1:cross:0:0:0".
+func ParseColoring(spec string) (Coloring, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+
+	switch name {
+	case "smooth":
+		return Smooth{}, nil
+	case "period":
+		return Period{}, nil
+	case "trap":
+		return parseTrapped(arg)
+	case "distance":
+		width := 1.0
+		if hasArg {
+			var err error
+			width, err = strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing distance width %q: %w", arg, err)
+			}
+		}
+		if width <= 0.0 {
+			return nil, fmt.Errorf("distance width must be positive, got %v", width)
+		}
+		return Distance{Width: width}, nil
+	default:
+		return nil, fmt.Errorf("unknown coloring %q", spec)
+	}
+}
+
+// Differentiable reports whether every step of m is transforms.Differentiable,
+// as Distance requires.
+func Differentiable(m transforms.ComplexMap) bool {
+	for _, step := range transforms.Steps(m) {
+		if _, ok := step.(transforms.Differentiable); !ok {
+			return false
+		}
+	}
+	return true
+}
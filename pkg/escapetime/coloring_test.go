@@ -0,0 +1,41 @@
+package escapetime
+
+import "testing"
+
+func TestParseColoring(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		want    Coloring
+		wantErr bool
+	}{
+		{spec: "smooth", want: Smooth{}},
+		{spec: "period", want: Period{}},
+		{spec: "distance", want: Distance{Width: 1}},
+		{spec: "distance:2.5", want: Distance{Width: 2.5}},
+		{spec: "trap:distance:0.1:point:0:0", want: Trapped{Mode: TrapDistance, Radius: 0.1, Trap: PointTrap{}}},
+		{spec: "distance:0", wantErr: true},
+		{spec: "distance:-1", wantErr: true},
+		{spec: "distance:thin", wantErr: true},
+		{spec: "trap", wantErr: true},
+		{spec: "", wantErr: true},
+		{spec: "escape", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		got, err := ParseColoring(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseColoring(%q) = %v, want error", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseColoring(%q): %v", tc.spec, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("ParseColoring(%q) = %#v, want %#v", tc.spec, got, tc.want)
+		}
+	}
+}
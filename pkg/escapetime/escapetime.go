@@ -0,0 +1,104 @@
+package escapetime
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"math"
+	"math/cmplx"
+)
+
+// Escape iterates m from z one step at a time, starting with step start of m, until
+// the orbit escapes bailout or maxIterations steps are applied.
+//
+// Returns the last point of the orbit and the number of steps applied.
+// The orbit escaped if the count is less than maxIterations.
+func Escape(m transforms.ComplexMap, start int, z complex128, bailout Bailout, maxIterations int) (complex128, int) {
+	steps := transforms.Steps(transforms.Start(m))
+
+	step := cmplx.Inf()
+	n := 0
+	for n < maxIterations && !bailout.Escaped(z, step) {
+		next := steps[(start+n)%len(steps)].Next(z)
+		z, step = next, next-z
+		n++
+	}
+
+	return z, n
+}
+
+// EscapeDerivative is Escape, also tracking the derivative dz/dz0 of the orbit's
+// last point with respect to its starting point.
+//
+// Returns false if a step of m isn't transforms.Differentiable, in which case
+// the derivative is meaningless.
+func EscapeDerivative(m transforms.ComplexMap, start int, z complex128, bailout Bailout, maxIterations int) (complex128, complex128, int, bool) {
+	steps := transforms.Steps(transforms.Start(m))
+
+	derivatives := make([]transforms.Differentiable, len(steps))
+	for i, step := range steps {
+		d, ok := step.(transforms.Differentiable)
+		if !ok {
+			return z, 0, 0, false
+		}
+		derivatives[i] = d
+	}
+
+	dz := complex(1, 0)
+	step := cmplx.Inf()
+	n := 0
+	for n < maxIterations && !bailout.Escaped(z, step) {
+		i := (start + n) % len(steps)
+		dz *= derivatives[i].Derivative(z)
+		next := steps[i].Next(z)
+		z, step = next, next-z
+		n++
+	}
+
+	return z, dz, n, true
+}
+
+// SmoothCount returns a continuous escape time for an orbit of m, from step start,
+// which escaped bailout to z after n steps, removing the banding of integer counts.
+//
+// Each step of degree d multiplies log|z| by about d, so an orbit's progress toward
+// escape is the sum of the log degrees of the steps applied, less the amount log log|z|
+// overshot log log bailout by. The count is that progress in steps of the mean log
+// degree of m's steps, which stays continuous even where steps of degree one, such as
+// Linear transforms, carry orbits over the bailout. Degrees below one count as one.
+// If no step of m has a degree above one, n is returned unchanged.
+//
+// Smoothing needs a bailout radius large enough that the leading term of each step
+// dominates, such as MaxRadius(m).
+func SmoothCount(m transforms.ComplexMap, start, n int, z complex128, bailout float64) float64 {
+	steps := transforms.Steps(m)
+
+	total := 0.0
+	for _, step := range steps {
+		total += logDegree(step)
+	}
+	if total == 0.0 {
+		return float64(n)
+	}
+
+	progress := float64(n/len(steps)) * total
+	for i := 0; i < n%len(steps); i++ {
+		progress += logDegree(steps[(start+i)%len(steps)])
+	}
+
+	excess := math.Log(math.Log(cmplx.Abs(z)) / math.Log(bailout))
+
+	return (progress - excess) * float64(len(steps)) / total
+}
+
+// logDegree is the log of the degree of m, or zero if m doesn't escape geometrically.
+func logDegree(m transforms.ComplexMap) float64 {
+	return math.Max(0.0, math.Log(m.Degree()))
+}
+
+// EscapeCount is the escape time of an orbit of m, from step start, which escaped
+// bailout: its SmoothCount for Radial bailouts, and its number of steps otherwise.
+func EscapeCount(m transforms.ComplexMap, start int, orbit Orbit, bailout Bailout) float64 {
+	if r, ok := bailout.(Radial); ok {
+		return SmoothCount(m, start, orbit.N, orbit.Z, r.Radius())
+	}
+	return float64(orbit.N)
+}
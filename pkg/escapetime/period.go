@@ -0,0 +1,129 @@
+package escapetime
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"math/cmplx"
+)
+
+// A CycleDetector finds when an orbit has fallen into a cycle, using Brent's algorithm.
+//
+// It compares each point to a saved point, saving a new one after runs of doubling
+// length, so it needs constant memory and finds a cycle within a few periods of
+// the orbit reaching it.
+type CycleDetector struct {
+	// Tolerance is how close points must be to count as the same.
+	Tolerance float64
+
+	saved  complex128
+	power  int
+	length int
+}
+
+// NewCycleDetector returns a CycleDetector for an orbit starting at z.
+func NewCycleDetector(z complex128, tolerance float64) CycleDetector {
+	return CycleDetector{Tolerance: tolerance, saved: z, power: 1}
+}
+
+// Check records the next point of the orbit.
+// Returns the period of the cycle the orbit has fallen into, or zero if none is found yet.
+func (d *CycleDetector) Check(z complex128) int {
+	d.length++
+	if cmplx.Abs(z-d.saved) < d.Tolerance {
+		return d.length
+	}
+
+	if d.length == d.power {
+		d.saved = z
+		d.power *= 2
+		d.length = 0
+	}
+
+	return 0
+}
+
+// An Orbit is the fate of an orbit followed by Iterate.
+type Orbit struct {
+	// Z is the last point of the orbit.
+	Z complex128
+
+	// N is the number of steps applied.
+	N int
+
+	// Escaped is whether the orbit reached the bailout.
+	Escaped bool
+
+	// Period is the number of steps in the cycle the orbit fell into,
+	// or zero if none was found.
+	Period int
+
+	// Multiplier is the derivative of the map around the cycle, whose magnitude is
+	// below one for attracting cycles. NaN if the map isn't transforms.Differentiable.
+	Multiplier complex128
+}
+
+// Iterate is Escape, also stopping early once the orbit falls into a cycle to within tolerance.
+// A tolerance of zero disables cycle detection.
+//
+// Cycles are only looked for after whole periods of m's steps. Orbits of Stateful maps
+// depend on more than their current point, so cycle detection is disabled for them.
+func Iterate(m transforms.ComplexMap, start int, z complex128, bailout Bailout, maxIterations int, tolerance float64) Orbit {
+	return Trace(m, start, z, bailout, maxIterations, tolerance, nil)
+}
+
+// Trace is Iterate, also passing each point after the start of the orbit to visit,
+// if set, along with the number of steps taken to reach it. Stops early if visit
+// returns false.
+func Trace(m transforms.ComplexMap, start int, z complex128, bailout Bailout, maxIterations int, tolerance float64, visit func(n int, z complex128) bool) Orbit {
+	steps := transforms.Steps(transforms.Start(m))
+	for _, step := range steps {
+		if _, ok := step.(transforms.Stateful); ok {
+			tolerance = 0.0
+		}
+	}
+
+	cycles := NewCycleDetector(z, tolerance)
+
+	step := cmplx.Inf()
+	n := 0
+	for n < maxIterations && !bailout.Escaped(z, step) {
+		next := steps[(start+n)%len(steps)].Next(z)
+		z, step = next, next-z
+		n++
+
+		if visit != nil && !visit(n, z) {
+			return Orbit{Z: z, N: n, Escaped: bailout.Escaped(z, step)}
+		}
+
+		if tolerance > 0.0 && n%len(steps) == 0 {
+			if period := cycles.Check(z); period > 0 {
+				period *= len(steps)
+				return Orbit{
+					Z:          z,
+					N:          n,
+					Period:     period,
+					Multiplier: multiplier(steps, start+n, z, period),
+				}
+			}
+		}
+	}
+
+	return Orbit{Z: z, N: n, Escaped: n < maxIterations}
+}
+
+// multiplier returns the derivative of period steps, starting with step start, at z.
+func multiplier(steps []transforms.ComplexMap, start int, z complex128, period int) complex128 {
+	d := complex(1, 0)
+	for i := 0; i < period; i++ {
+		step := steps[(start+i)%len(steps)]
+
+		ds, ok := step.(transforms.Differentiable)
+		if !ok {
+			return cmplx.NaN()
+		}
+
+		d *= ds.Derivative(z)
+		z = step.Next(z)
+	}
+
+	return d
+}
@@ -0,0 +1,158 @@
+package escapetime
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"math/cmplx"
+	"testing"
+)
+
+func TestCycleDetector(t *testing.T) {
+	for _, period := range []int{1, 2, 3, 7, 20} {
+		// An orbit which reaches a cycle after a few points.
+		orbit := func(i int) complex128 {
+			if i < 5 {
+				return complex(float64(100+i), 0)
+			}
+			return complex(float64(i%period), 0)
+		}
+
+		d := NewCycleDetector(orbit(0), 1e-9)
+		found := 0
+		for i := 1; i < 1000 && found == 0; i++ {
+			found = d.Check(orbit(i))
+		}
+
+		if found != period {
+			t.Errorf("found period %d, want %d", found, period)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	tcs := []struct {
+		name           string
+		m              transforms.ComplexMap
+		wantPeriod     int
+		wantMultiplier complex128
+	}{
+		{
+			// 0 -> -1 -> 0, a superattracting cycle.
+			name:           "z^2 - 1",
+			m:              transforms.Julia2{C: -1},
+			wantPeriod:     2,
+			wantMultiplier: 0,
+		},
+		{
+			// Attracted to the fixed point (1 - sqrt(2))/2, where the derivative is 1 - sqrt(2).
+			name:           "z^2 - 0.25",
+			m:              transforms.Julia2{C: -0.25 + 0i},
+			wantPeriod:     1,
+			wantMultiplier: complex(1-1.4142135623730951, 0),
+		},
+		{
+			// Periods are counted in steps, not in passes over the cycle of maps.
+			name:           "alternating z^2 - 1",
+			m:              transforms.Alternate(transforms.Julia2{C: -1}, transforms.Julia2{C: -1}),
+			wantPeriod:     2,
+			wantMultiplier: 0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Iterate(tc.m, 0, 0, Radius{R: 2}, 10000, 1e-12)
+
+			if got.Escaped {
+				t.Errorf("got escaped orbit, want a cycle")
+			}
+			if got.Period != tc.wantPeriod {
+				t.Errorf("got period %d, want %d", got.Period, tc.wantPeriod)
+			}
+			if cmplx.Abs(got.Multiplier-tc.wantMultiplier) > 1e-6 {
+				t.Errorf("got multiplier %v, want %v", got.Multiplier, tc.wantMultiplier)
+			}
+			if got.N == 10000 {
+				t.Errorf("got %d iterations, want it to stop at the cycle", got.N)
+			}
+		})
+	}
+}
+
+func TestIterate_Stateful(t *testing.T) {
+	// Phoenix with P zero is z^2 - 1, but depends on its history in general, so its
+	// cycles aren't looked for.
+	got := Iterate(transforms.Phoenix{C: -1}, 0, 0, Radius{R: 2}, 1000, 1e-12)
+
+	if got.Period != 0 {
+		t.Errorf("got period %d, want cycle detection disabled", got.Period)
+	}
+	if got.N != 1000 || got.Escaped {
+		t.Errorf("got %d iterations, escaped %t; want 1000, false", got.N, got.Escaped)
+	}
+}
+
+func TestIterate_Escapes(t *testing.T) {
+	got := Iterate(transforms.Julia2{C: 1}, 0, 0, Radius{R: 2}, 1000, 1e-12)
+
+	if !got.Escaped || got.Period != 0 {
+		t.Errorf("got escaped %t with period %d, want escaped with no period", got.Escaped, got.Period)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	tcs := []struct {
+		name      string
+		m         transforms.ComplexMap
+		stopAfter int
+		want      []complex128
+	}{
+		{
+			// 0 -> 1 -> 2, which reaches the bailout.
+			name: "escaping",
+			m:    transforms.Julia2{C: 1},
+			want: []complex128{1, 2},
+		},
+		{
+			// Each step of the cycle of maps is visited.
+			name: "alternating",
+			m:    transforms.Alternate(transforms.Julia2{C: 1}, transforms.Linear{Multiply: 0.5}),
+			want: []complex128{1, 0.5, 1.25, 0.625, 1.390625, 0.6953125},
+		},
+		{
+			name:      "stopped",
+			m:         transforms.Julia2{C: -1},
+			stopAfter: 3,
+			want:      []complex128{-1, 0, -1},
+		},
+		{
+			// Phoenix depends on its history, so it must be followed to the end rather
+			// than stopped at a cycle.
+			name: "stateful",
+			m:    transforms.Phoenix{C: -1},
+			want: []complex128{-1, 0, -1, 0, -1, 0},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []complex128
+			orbit := Trace(tc.m, 0, 0, Radius{R: 2}, 6, 1e-12, func(n int, z complex128) bool {
+				got = append(got, z)
+				if n != len(got) {
+					t.Errorf("visited point %d as point %d", len(got), n)
+				}
+				return n != tc.stopAfter
+			})
+
+			if len(got) != len(tc.want) || orbit.N != len(tc.want) {
+				t.Fatalf("visited %v in %d steps, want %v", got, orbit.N, tc.want)
+			}
+			for i := range got {
+				if cmplx.Abs(got[i]-tc.want[i]) > 1e-12 {
+					t.Errorf("visited %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
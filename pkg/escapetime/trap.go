@@ -0,0 +1,348 @@
+package escapetime
+
+import (
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/cmplx"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// trapUsage describes the syntax accepted by parseTrapped.
+const trapUsage = `trap:MODE:RADIUS:SHAPE (orbit traps, which orbits enter within RADIUS; ` +
+	`MODE is distance (how close orbits pass), first (the iteration orbits first enter), ` +
+	`or position (where in the trap orbits are caught); ` +
+	`SHAPE is point:X:Y, line:X:Y:ANGLE, cross:X:Y:ANGLE, circle:X:Y:R, or image:PATH:X:Y:HEIGHT)`
+
+// A Trap is a shape in the plane which orbits can pass close to.
+type Trap interface {
+	// Distance returns how far z is from the trap.
+	Distance(z complex128) float64
+
+	// Position returns where z is relative to the trap, in [0, 1].
+	Position(z complex128) float64
+}
+
+var (
+	_ Trap = PointTrap{}
+	_ Trap = LineTrap{}
+	_ Trap = CrossTrap{}
+	_ Trap = CircleTrap{}
+	_ Trap = ImageTrap{}
+)
+
+// PointTrap is a single point. Positions are angles around it.
+type PointTrap struct {
+	Z complex128
+}
+
+func (t PointTrap) Distance(z complex128) float64 {
+	return cmplx.Abs(z - t.Z)
+}
+
+func (t PointTrap) Position(z complex128) float64 {
+	return turns(z - t.Z)
+}
+
+// LineTrap is the line through Z at Angle radians counter-clockwise from the real axis.
+// Positions are along the line, with Z at one half.
+type LineTrap struct {
+	Z     complex128
+	Angle float64
+}
+
+func (t LineTrap) Distance(z complex128) float64 {
+	return math.Abs(imag(t.local(z)))
+}
+
+func (t LineTrap) Position(z complex128) float64 {
+	return 0.5 + math.Atan(real(t.local(z)))/math.Pi
+}
+
+// local returns z in coordinates where the line is the real axis.
+func (t LineTrap) local(z complex128) complex128 {
+	return (z - t.Z) * cmplx.Rect(1.0, -t.Angle)
+}
+
+// CrossTrap is a pair of perpendicular lines crossing at Z, the first at Angle radians.
+// Positions are along whichever line is nearer.
+type CrossTrap struct {
+	Z     complex128
+	Angle float64
+}
+
+func (t CrossTrap) Distance(z complex128) float64 {
+	return t.nearer(z).Distance(z)
+}
+
+func (t CrossTrap) Position(z complex128) float64 {
+	return t.nearer(z).Position(z)
+}
+
+func (t CrossTrap) nearer(z complex128) LineTrap {
+	first := LineTrap{Z: t.Z, Angle: t.Angle}
+	second := LineTrap{Z: t.Z, Angle: t.Angle + 0.5*math.Pi}
+	if second.Distance(z) < first.Distance(z) {
+		return second
+	}
+	return first
+}
+
+// CircleTrap is the circle of radius R around Z. Positions are angles around Z.
+type CircleTrap struct {
+	Z complex128
+	R float64
+}
+
+func (t CircleTrap) Distance(z complex128) float64 {
+	return math.Abs(cmplx.Abs(z-t.Z) - t.R)
+}
+
+func (t CircleTrap) Position(z complex128) float64 {
+	return turns(z - t.Z)
+}
+
+// turns is the angle of z in turns, in [0, 1).
+func turns(z complex128) float64 {
+	return 0.5 + cmplx.Phase(z)/(2.0*math.Pi)
+}
+
+// ImageTrap is a bitmap placed in the plane, centered on Z and Height tall.
+//
+// Points on opaque pixels are in the trap, and points anywhere else are infinitely
+// far from it. Positions are the brightness of the pixel under the point.
+type ImageTrap struct {
+	Z      complex128
+	Height float64
+
+	width, height int
+	// brightness is row-major with the top row first, and negative for transparent pixels.
+	brightness []float64
+}
+
+// NewImageTrap places img in the plane centered on z and height tall.
+func NewImageTrap(img image.Image, z complex128, height float64) ImageTrap {
+	bounds := img.Bounds()
+	t := ImageTrap{
+		Z:          z,
+		Height:     height,
+		width:      bounds.Dx(),
+		height:     bounds.Dy(),
+		brightness: make([]float64, bounds.Dx()*bounds.Dy()),
+	}
+
+	for y := 0; y < t.height; y++ {
+		for x := 0; x < t.width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+
+			brightness := -1.0
+			if a >= 0x8000 {
+				// Rec. 709 luma of the color with alpha removed.
+				brightness = (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)) / float64(a)
+			}
+			t.brightness[x+y*t.width] = brightness
+		}
+	}
+
+	return t
+}
+
+// LoadImageTrap reads a PNG or JPEG file and places it as NewImageTrap does.
+func LoadImageTrap(path string, z complex128, height float64) (ImageTrap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageTrap{}, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return ImageTrap{}, fmt.Errorf("decoding trap image %q: %w", path, err)
+	}
+
+	return NewImageTrap(img, z, height), nil
+}
+
+func (t ImageTrap) Distance(z complex128) float64 {
+	if t.at(z) < 0.0 {
+		return math.Inf(1)
+	}
+	return 0.0
+}
+
+func (t ImageTrap) Position(z complex128) float64 {
+	return math.Max(0.0, t.at(z))
+}
+
+// at returns the brightness of the pixel under z, or -1 if there is no opaque pixel there.
+func (t ImageTrap) at(z complex128) float64 {
+	scale := float64(t.height) / t.Height
+	x := math.Floor(real(z-t.Z)*scale + 0.5*float64(t.width))
+	y := math.Floor(0.5*float64(t.height) - imag(z-t.Z)*scale)
+
+	if x < 0.0 || y < 0.0 || x >= float64(t.width) || y >= float64(t.height) {
+		return -1.0
+	}
+
+	return t.brightness[int(x)+int(y)*t.width]
+}
+
+// TrapMode is what a Trapped coloring measures about orbits passing through its Trap.
+type TrapMode int
+
+const (
+	// TrapDistance measures how close orbits come to the trap.
+	TrapDistance TrapMode = iota
+	// TrapFirst measures the iteration orbits first enter the trap.
+	TrapFirst
+	// TrapPosition measures where in the trap orbits are first caught.
+	TrapPosition
+)
+
+// Trapped colors orbits by how they pass through a Trap, which they enter by coming
+// within Radius of it. Orbits are followed whether or not they escape.
+//
+// With TrapDistance, samples fall off linearly from one for orbits passing through
+// the trap to zero for orbits never within Radius. With TrapFirst, samples are the
+// iteration orbits enter the trap, and with TrapPosition the Trap's Position of the
+// point they enter it at. Orbits which never enter the trap are zero.
+//
+// The starting point of an orbit is not checked against the trap, only the points
+// after it. In a parameter plane every orbit starts from the same critical point,
+// so a trap around it would otherwise catch every orbit at once.
+type Trapped struct {
+	Trap   Trap
+	Mode   TrapMode
+	Radius float64
+}
+
+func (t Trapped) Value(m transforms.ComplexMap, start int, z complex128, o Options) float64 {
+	nearest := math.Inf(1)
+	value := 0.0
+
+	Trace(m, start, z, o.Bailout, o.MaxIterations, o.Tolerance, func(n int, z complex128) bool {
+		distance := t.Trap.Distance(z)
+
+		switch t.Mode {
+		case TrapFirst:
+			if distance < t.Radius {
+				value = float64(n)
+				return false
+			}
+		case TrapPosition:
+			if distance < t.Radius {
+				value = t.Trap.Position(z)
+				return false
+			}
+		default:
+			nearest = math.Min(nearest, distance)
+		}
+
+		return true
+	})
+
+	if t.Mode == TrapDistance {
+		return math.Max(0.0, 1.0-nearest/t.Radius)
+	}
+	return value
+}
+
+// parseTrapped parses the MODE:RADIUS:SHAPE part of a trap coloring.
+func parseTrapped(spec string) (Trapped, error) {
+	// The shape's fields are split by parseTrap, as image paths may hold colons.
+	fields := strings.SplitN(spec, ":", 4)
+	if len(fields) < 3 {
+		return Trapped{}, fmt.Errorf("parsing trap %q: want %s", spec, trapUsage)
+	}
+
+	var result Trapped
+
+	switch fields[0] {
+	case "distance":
+		result.Mode = TrapDistance
+	case "first":
+		result.Mode = TrapFirst
+	case "position":
+		result.Mode = TrapPosition
+	default:
+		return Trapped{}, fmt.Errorf("unknown trap mode %q, want distance, first, or position", fields[0])
+	}
+
+	radius, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return Trapped{}, fmt.Errorf("parsing trap radius %q: %w", fields[1], err)
+	}
+	if radius <= 0.0 {
+		return Trapped{}, fmt.Errorf("trap radius must be positive, got %v", radius)
+	}
+	result.Radius = radius
+
+	var shape string
+	if len(fields) == 4 {
+		shape = fields[3]
+	}
+	result.Trap, err = parseTrap(fields[2], shape)
+	if err != nil {
+		return Trapped{}, err
+	}
+
+	return result, nil
+}
+
+// parseTrap parses the trap shape name with the colon-separated fields after it.
+// The numbers of an image trap are its last three fields, so its path may hold colons.
+func parseTrap(name string, spec string) (Trap, error) {
+	var fields []string
+	if spec != "" {
+		fields = strings.Split(spec, ":")
+	}
+
+	var path string
+	if name == "image" && len(fields) > 3 {
+		n := len(fields) - 3
+		path, fields = strings.Join(fields[:n], ":"), fields[n:]
+	}
+
+	args := make([]float64, len(fields))
+	for i, field := range fields {
+		arg, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing argument %q of %s trap: %w", field, name, err)
+		}
+		args[i] = arg
+	}
+
+	wantArgs := map[string]int{"point": 2, "line": 3, "cross": 3, "circle": 3, "image": 3}
+	want, ok := wantArgs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown trap shape %q, want point, line, cross, circle, or image", name)
+	}
+	if name == "image" && path == "" {
+		return nil, fmt.Errorf("image trap needs a file path")
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("%s trap takes %d numbers, got %d", name, want, len(args))
+	}
+
+	z := complex(args[0], args[1])
+	switch name {
+	case "point":
+		return PointTrap{Z: z}, nil
+	case "line":
+		return LineTrap{Z: z, Angle: args[2]}, nil
+	case "cross":
+		return CrossTrap{Z: z, Angle: args[2]}, nil
+	case "circle":
+		return CircleTrap{Z: z, R: args[2]}, nil
+	default:
+		if args[2] <= 0.0 {
+			return nil, fmt.Errorf("image trap height must be positive, got %v", args[2])
+		}
+		return LoadImageTrap(path, z, args[2])
+	}
+}
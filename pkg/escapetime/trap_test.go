@@ -0,0 +1,106 @@
+package escapetime
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/transforms"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTrapped(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		want    Trapped
+		wantErr bool
+	}{
+		{spec: "distance:0.1:point:0:0", want: Trapped{Mode: TrapDistance, Radius: 0.1, Trap: PointTrap{}}},
+		{spec: "first:0.5:line:1:-1:0.5", want: Trapped{Mode: TrapFirst, Radius: 0.5, Trap: LineTrap{Z: complex(1, -1), Angle: 0.5}}},
+		{spec: "position:1:cross:0:0:0", want: Trapped{Mode: TrapPosition, Radius: 1, Trap: CrossTrap{}}},
+		{spec: "distance:0.2:circle:0:1:0.5", want: Trapped{Mode: TrapDistance, Radius: 0.2, Trap: CircleTrap{Z: 1i, R: 0.5}}},
+		{spec: "distance:0.1", wantErr: true},
+		{spec: "nearest:0.1:point:0:0", wantErr: true},
+		{spec: "distance:0:point:0:0", wantErr: true},
+		{spec: "distance:wide:point:0:0", wantErr: true},
+		{spec: "distance:0.1:point", wantErr: true},
+		{spec: "distance:0.1:point:0", wantErr: true},
+		{spec: "distance:0.1:point:0:zero", wantErr: true},
+		{spec: "distance:0.1:star:0:0", wantErr: true},
+		{spec: "distance:0.1:image:0:0:1", wantErr: true},
+		{spec: "distance:0.1:image:missing.png:0:0:1", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		got, err := parseTrapped(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseTrapped(%q) = %v, want error", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTrapped(%q): %v", tc.spec, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("parseTrapped(%q) = %#v, want %#v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseTrapped_Image(t *testing.T) {
+	// Paths may hold colons.
+	path := filepath.Join(t.TempDir(), "trap:1.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = png.Encode(f, image.NewGray(image.Rect(0, 0, 4, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseTrapped("position:0.5:image:" + path + ":0:0:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Trap.(ImageTrap); !ok {
+		t.Errorf("got trap %T, want ImageTrap", got.Trap)
+	}
+
+	_, err = parseTrapped("position:0.5:image:" + path + ":0:0:0")
+	if err == nil {
+		t.Error("got nil error for an image trap of zero height")
+	}
+}
+
+func TestTrapped_Start(t *testing.T) {
+	// The orbit 0 -> 1 -> 2 starts in the first trap, but only later points count.
+	m := transforms.Julia2{C: 1}
+	o := Options{Bailout: Radius{R: 2}, MaxIterations: 10}
+
+	tcs := []struct {
+		name string
+		trap Trap
+		want float64
+	}{
+		{name: "start", trap: PointTrap{Z: 0}, want: 0},
+		{name: "first step", trap: PointTrap{Z: 1}, want: 1},
+		{name: "second step", trap: PointTrap{Z: 2}, want: 2},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			trapped := Trapped{Trap: tc.trap, Mode: TrapFirst, Radius: 0.5}
+			if got := trapped.Value(m, 0, 0, o); got != tc.want {
+				t.Errorf("got first entry %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
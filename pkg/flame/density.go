@@ -0,0 +1,86 @@
+package flame
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"math"
+)
+
+// Estimator blurs the samples of each pixel over a radius which shrinks as the
+// pixel's count grows, smoothing the noise of sparsely sampled regions while
+// keeping densely sampled ones sharp. This is flam3's density estimation.
+type Estimator struct {
+	// MaxRadius is the radius in pixels of the blur of pixels with one sample.
+	// Zero disables density estimation.
+	MaxRadius float64 `json:"maxRadius"`
+
+	// MinRadius is the smallest radius of blur, however dense the pixel.
+	MinRadius float64 `json:"minRadius,omitempty"`
+
+	// Curve is how fast radii shrink with density: as the count to the power -Curve.
+	Curve float64 `json:"curve"`
+}
+
+// Apply returns the result of blurring b, whose channels are those of a flame.
+// Returns b itself if density estimation is disabled.
+func (e Estimator) Apply(b *accum.Buffer) *accum.Buffer {
+	if e.MaxRadius <= 0.0 {
+		return b
+	}
+
+	out := accum.New(b.Width, b.Height, b.Channels)
+	counts := b.Channel(Count)
+
+	var weights []float64
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			i := x + y*b.Width
+			count := counts[i]
+			if count <= 0.0 {
+				continue
+			}
+
+			// Splats can leave fractions of a sample, which blur no wider than whole ones.
+			r := e.MaxRadius / math.Pow(math.Max(count, 1.0), e.Curve)
+			r = math.Max(r, e.MinRadius)
+
+			if r < 0.5 {
+				// The blur wouldn't reach the neighboring pixels.
+				for c := 0; c < b.Channels; c++ {
+					out.Add(x, y, c, b.Data[c*b.Width*b.Height+i])
+				}
+				continue
+			}
+
+			// A Gaussian with standard deviation r/2, cut off at r.
+			extent := int(math.Ceil(r))
+			weights = weights[:0]
+			total := 0.0
+			for dy := -extent; dy <= extent; dy++ {
+				for dx := -extent; dx <= extent; dx++ {
+					d2 := float64(dx*dx+dy*dy) / (r * r)
+					w := 0.0
+					if d2 <= 1.0 {
+						w = math.Exp(-2.0 * d2)
+					}
+					weights = append(weights, w)
+					total += w
+				}
+			}
+
+			for c := 0; c < b.Channels; c++ {
+				v := b.Data[c*b.Width*b.Height+i] / total
+				k := 0
+				for dy := -extent; dy <= extent; dy++ {
+					for dx := -extent; dx <= extent; dx++ {
+						if weights[k] > 0.0 {
+							out.Add(x+dx, y+dy, c, v*weights[k])
+						}
+						k++
+					}
+				}
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,89 @@
+package flame
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"math"
+	"testing"
+)
+
+func TestEstimator_Apply(t *testing.T) {
+	tcs := []struct {
+		name      string
+		estimator Estimator
+		count     float64
+		// wantRadius is how far the pixel's samples should spread, or zero if they
+		// should stay put.
+		wantRadius int
+	}{
+		{name: "sparse", estimator: Estimator{MaxRadius: 3, Curve: 0.5}, count: 1, wantRadius: 3},
+		// Fractions of a sample blur as far as a whole one.
+		{name: "fraction", estimator: Estimator{MaxRadius: 3, Curve: 0.5}, count: 0.25, wantRadius: 3},
+		// The radius is 3/sqrt(9) = 1.
+		{name: "denser", estimator: Estimator{MaxRadius: 3, Curve: 0.5}, count: 9, wantRadius: 1},
+		// The radius of 3/sqrt(100) is below half a pixel.
+		{name: "dense", estimator: Estimator{MaxRadius: 3, Curve: 0.5}, count: 100},
+		{name: "min radius", estimator: Estimator{MaxRadius: 3, MinRadius: 2, Curve: 0.5}, count: 100, wantRadius: 2},
+	}
+
+	const size, center = 11, 5
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			b := accum.New(size, size, Channels)
+			b.Add(center, center, Red, 0.5*tc.count)
+			b.Add(center, center, Count, tc.count)
+
+			out := tc.estimator.Apply(b)
+
+			for c := 0; c < Channels; c++ {
+				in, got := b.Channel(c), out.Channel(c)
+
+				total, want := 0.0, 0.0
+				for i := range got {
+					total += got[i]
+					want += in[i]
+
+					x, y := i%size-center, i/size-center
+					distance := max(abs(x), abs(y))
+					if got[i] != 0.0 && distance > tc.wantRadius {
+						t.Errorf("channel %d spread to (%d, %d), beyond %d pixels", c, x, y, tc.wantRadius)
+					}
+				}
+
+				// Blurring moves samples around without adding or losing any.
+				if math.Abs(total-want) > 1e-9 {
+					t.Errorf("channel %d totals %v, want %v", c, total, want)
+				}
+			}
+
+			// Samples spread as far as the radius along the axes.
+			if tc.wantRadius > 0 && out.Channel(Count)[center*size+center+tc.wantRadius] == 0.0 {
+				t.Errorf("samples didn't spread %d pixels", tc.wantRadius)
+			}
+
+			// Colours stay in proportion to counts.
+			counts, red := out.Channel(Count), out.Channel(Red)
+			for i := range counts {
+				if math.Abs(red[i]-0.5*counts[i]) > 1e-12 {
+					t.Errorf("pixel %d has red %v for count %v", i, red[i], counts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEstimator_Disabled(t *testing.T) {
+	b := accum.New(3, 3, Channels)
+	b.Add(1, 1, Count, 1)
+
+	if out := (Estimator{}).Apply(b); out != b {
+		t.Error("got a new buffer, want density estimation disabled")
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
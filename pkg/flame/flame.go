@@ -0,0 +1,82 @@
+// Package flame turns the samples of a fractal flame into an image, as in Draves
+// and Reckase's "The Fractal Flame Algorithm" and its reference renderer flam3.
+//
+// Samples are accumulated into an accum.Buffer with the Channels below: the sums
+// of the red, green, and blue of each sample's colour, and the number of samples.
+// Dividing the colour sums by the count gives a pixel's average colour, while the
+// count alone gives its density.
+package flame
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"image"
+	"math"
+)
+
+// The channels of a flame's accumulation buffer.
+const (
+	Red = iota
+	Green
+	Blue
+	Count
+
+	// Channels is the number of channels a flame's buffer needs.
+	Channels
+)
+
+// whiteLevel is the density flam3 scales counts against, kept so that brightness,
+// gamma, and vibrancy look the same as in flam3.
+const whiteLevel = 255.0
+
+// ToneMap maps the log of each pixel's density to its brightness, so that both
+// the sparse and the dense parts of a flame show, and then gamma corrects it.
+type ToneMap struct {
+	// Brightness scales the log density.
+	Brightness float64
+
+	// Gamma brightens faint parts of the flame. One leaves densities as they are.
+	Gamma float64
+
+	// Vibrancy, in [0, 1], is how much gamma correction is applied to densities
+	// rather than to each colour channel. One keeps colours saturated where the
+	// flame is faint; zero washes them out toward white where it is bright.
+	Vibrancy float64
+}
+
+// Render tone maps b, which accumulated samplesPerPixel samples per pixel on average,
+// counting samples which landed outside the image. The normalization makes the
+// brightness of the result independent of how many samples were taken.
+func (t ToneMap) Render(b *accum.Buffer, samplesPerPixel float64) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, b.Width, b.Height))
+
+	red, green, blue := b.Channel(Red), b.Channel(Green), b.Channel(Blue)
+	counts := b.Channel(Count)
+
+	k2 := 1.0 / (whiteLevel * samplesPerPixel)
+	invGamma := 1.0 / t.Gamma
+
+	for i, count := range counts {
+		if count <= 0.0 {
+			img.SetRGBA64(i%b.Width, i/b.Width, palette.RGB{}.RGBA64())
+			continue
+		}
+
+		// Scaling by ls replaces each pixel's count with the log of its density.
+		ls := t.Brightness * math.Log1p(count*k2) / count
+		alpha := count * ls
+
+		// The gamma corrected alpha, divided by alpha.
+		g := math.Pow(alpha, invGamma) / alpha
+
+		channel := func(sum float64) float64 {
+			v := sum * ls
+			return t.Vibrancy*v*g + (1.0-t.Vibrancy)*math.Pow(v, invGamma)
+		}
+
+		c := palette.RGB{R: channel(red[i]), G: channel(green[i]), B: channel(blue[i])}
+		img.SetRGBA64(i%b.Width, i/b.Width, c.RGBA64())
+	}
+
+	return img
+}
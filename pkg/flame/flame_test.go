@@ -0,0 +1,125 @@
+package flame
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// pixel returns a 1x1 flame buffer of count samples averaging c.
+func pixel(count float64, c palette.RGB) *accum.Buffer {
+	b := accum.New(1, 1, Channels)
+	b.Add(0, 0, Red, count*c.R)
+	b.Add(0, 0, Green, count*c.G)
+	b.Add(0, 0, Blue, count*c.B)
+	b.Add(0, 0, Count, count)
+
+	return b
+}
+
+// unitDensity is the count whose log density is one for one sample per pixel.
+var unitDensity = whiteLevel * (math.E - 1)
+
+func TestToneMap_Render(t *testing.T) {
+	tcs := []struct {
+		name            string
+		toneMap         ToneMap
+		count           float64
+		samplesPerPixel float64
+		c               palette.RGB
+		want            palette.RGB
+	}{
+		{
+			name:            "empty",
+			toneMap:         ToneMap{Brightness: 1, Gamma: 1, Vibrancy: 1},
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 1},
+			want:            palette.RGB{},
+		},
+		{
+			// At unit log density, a pixel is its average colour.
+			name:            "log density",
+			toneMap:         ToneMap{Brightness: 1, Gamma: 1, Vibrancy: 1},
+			count:           unitDensity,
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 0.5, G: 0.25, B: 1},
+			want:            palette.RGB{R: 0.5, G: 0.25, B: 1},
+		},
+		{
+			// Taking twice the samples everywhere doesn't brighten the flame.
+			name:            "normalized",
+			toneMap:         ToneMap{Brightness: 1, Gamma: 1, Vibrancy: 1},
+			count:           2 * unitDensity,
+			samplesPerPixel: 2,
+			c:               palette.RGB{R: 0.5, G: 0.25, B: 1},
+			want:            palette.RGB{R: 0.5, G: 0.25, B: 1},
+		},
+		{
+			name:            "brightness",
+			toneMap:         ToneMap{Brightness: 0.5, Gamma: 1, Vibrancy: 1},
+			count:           unitDensity,
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 1, G: 0.5},
+			want:            palette.RGB{R: 0.5, G: 0.25},
+		},
+		{
+			// Log densities of e^2 - 1 times as many samples double.
+			name:            "log",
+			toneMap:         ToneMap{Brightness: 0.25, Gamma: 1, Vibrancy: 1},
+			count:           whiteLevel * (math.E*math.E - 1),
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 1},
+			want:            palette.RGB{R: 0.5},
+		},
+		{
+			// With full vibrancy, gamma brightens the density of 0.25 to 0.5 and
+			// scales every channel alike, keeping the hue.
+			name:            "vibrant gamma",
+			toneMap:         ToneMap{Brightness: 0.25, Gamma: 2, Vibrancy: 1},
+			count:           unitDensity,
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 1, G: 0.25},
+			want:            palette.RGB{R: 0.5, G: 0.125},
+		},
+		{
+			// Without vibrancy, gamma brightens each channel on its own, washing
+			// colours out.
+			name:            "channel gamma",
+			toneMap:         ToneMap{Brightness: 0.25, Gamma: 2, Vibrancy: 0},
+			count:           unitDensity,
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 1, G: 0.25},
+			want:            palette.RGB{R: 0.5, G: 0.25},
+		},
+		{
+			name:            "half vibrancy",
+			toneMap:         ToneMap{Brightness: 0.25, Gamma: 2, Vibrancy: 0.5},
+			count:           unitDensity,
+			samplesPerPixel: 1,
+			c:               palette.RGB{R: 1, G: 0.25},
+			want:            palette.RGB{R: 0.5, G: 0.1875},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			img := tc.toneMap.Render(pixel(tc.count, tc.c), tc.samplesPerPixel)
+
+			got, want := img.RGBA64At(0, 0), tc.want.RGBA64()
+			if !close16(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// close16 returns whether a and b differ by at most rounding.
+func close16(a, b color.RGBA64) bool {
+	near := func(x, y uint16) bool {
+		return x-y <= 1 || y-x <= 1
+	}
+
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && a.A == b.A
+}
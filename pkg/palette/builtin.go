@@ -0,0 +1,63 @@
+package palette
+
+import (
+	"sort"
+)
+
+func stops(hexes ...string) []Stop {
+	result := make([]Stop, len(hexes))
+	for i, hex := range hexes {
+		result[i] = Stop{
+			Position: float64(i) / float64(len(hexes)-1),
+			Color:    mustHex(hex),
+		}
+	}
+
+	return result
+}
+
+// Builtin palettes by name.
+var Builtin = map[string]Palette{
+	"gray": Gradient{Space: SRGB, Stops: stops("#000000", "#ffffff")},
+
+	// lightblue scales a single light blue, as cmd/julia has always drawn.
+	"lightblue": Gradient{Space: SRGB, Stops: stops("#000000", "#7fafff")},
+
+	// escape saturates blue, then brightens to white.
+	"escape": Gradient{Space: SRGB, Stops: stops("#000000", "#0000ff", "#ffffff")},
+
+	// poincare ramps through blue and magenta to white.
+	"poincare": Gradient{Space: SRGB, Stops: []Stop{
+		{Position: 0.0, Color: mustHex("#000000")},
+		{Position: 0.5, Color: mustHex("#0000ff")},
+		{Position: 0.75, Color: mustHex("#ff00ff")},
+		{Position: 1.0, Color: mustHex("#ffffff")},
+	}},
+
+	// flame cycles through bright hues, for the colour coordinates of fractal flames,
+	// where no colour should be black.
+	"flame": Gradient{Space: OKLab, Stops: stops(
+		"#ff4010", "#ffb020", "#40e0a0", "#3080ff", "#c040ff", "#ff4010")},
+
+	// viridis and inferno are sampled from the matplotlib colormaps.
+	"viridis": Gradient{Space: OKLab, Stops: stops(
+		"#440154", "#482878", "#3e4989", "#31688e", "#26828e",
+		"#1f9e89", "#35b779", "#6ece58", "#fde725")},
+	"inferno": Gradient{Space: OKLab, Stops: stops(
+		"#000004", "#1b0c41", "#4a0c6b", "#781c6d", "#a52c60",
+		"#cf4446", "#ed6925", "#fb9b06", "#fcffa4")},
+	"magma": Gradient{Space: OKLab, Stops: stops(
+		"#000004", "#180f3d", "#440f76", "#721f81", "#9e2f7f",
+		"#cd4071", "#f1605d", "#fd9668", "#fcfdbf")},
+}
+
+// BuiltinNames returns the sorted names of the Builtin palettes.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(Builtin))
+	for name := range Builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,257 @@
+package palette
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A GIMPGradient is a gradient in the format of GIMP's .ggr files.
+type GIMPGradient struct {
+	Name     string
+	Segments []GIMPSegment
+}
+
+// A GIMPSegment blends between two colours over [Left, Right].
+type GIMPSegment struct {
+	// Left, Middle, and Right are the positions of the segment's ends and midpoint.
+	Left, Middle, Right float64
+
+	LeftColor, RightColor RGB
+
+	// Blend is how the segment is shaped between its ends:
+	// 0 linear, 1 curved, 2 sine, 3 sphere increasing, 4 sphere decreasing, 5 step.
+	Blend int
+
+	// Coloring is how colours are mixed: 0 RGB, 1 HSV counter-clockwise, 2 HSV clockwise.
+	Coloring int
+}
+
+var _ Palette = GIMPGradient{}
+
+func (g GIMPGradient) At(t float64) color.RGBA64 {
+	t = clamp(t)
+
+	for _, s := range g.Segments {
+		if t <= s.Right {
+			return s.at(t).RGBA64()
+		}
+	}
+
+	if len(g.Segments) == 0 {
+		return color.RGBA64{A: 0xffff}
+	}
+	return g.Segments[len(g.Segments)-1].RightColor.RGBA64()
+}
+
+func (s GIMPSegment) at(t float64) RGB {
+	length := s.Right - s.Left
+	if length <= 0.0 {
+		return s.RightColor
+	}
+
+	// Position within the segment, then remapped so Middle lands at 0.5.
+	pos := (t - s.Left) / length
+	middle := (s.Middle - s.Left) / length
+
+	var f float64
+	switch s.Blend {
+	case 1:
+		f = math.Pow(pos, math.Log(0.5)/math.Log(middle))
+	case 2:
+		f = (math.Sin(-math.Pi/2+math.Pi*linearBlend(middle, pos)) + 1.0) / 2.0
+	case 3:
+		pos = linearBlend(middle, pos) - 1.0
+		f = math.Sqrt(1.0 - pos*pos)
+	case 4:
+		pos = linearBlend(middle, pos)
+		f = 1.0 - math.Sqrt(1.0-pos*pos)
+	case 5:
+		if pos >= middle {
+			f = 1.0
+		}
+	default:
+		f = linearBlend(middle, pos)
+	}
+
+	switch s.Coloring {
+	case 1, 2:
+		return lerpHSV(s.LeftColor, s.RightColor, f, s.Coloring == 2)
+	default:
+		return lerpRGB(s.LeftColor, s.RightColor, f)
+	}
+}
+
+func linearBlend(middle, pos float64) float64 {
+	if pos <= middle {
+		if middle < 1e-10 {
+			return 0.0
+		}
+		return 0.5 * pos / middle
+	}
+
+	if 1.0-middle < 1e-10 {
+		return 1.0
+	}
+	return 0.5 + 0.5*(pos-middle)/(1.0-middle)
+}
+
+func lerpHSV(from, to RGB, f float64, clockwise bool) RGB {
+	h0, s0, v0 := toHSV(from)
+	h1, s1, v1 := toHSV(to)
+
+	// Go around the hue circle in the requested direction.
+	if clockwise {
+		if h1 >= h0 {
+			h1 -= 1.0
+		}
+	} else if h1 <= h0 {
+		h1 += 1.0
+	}
+
+	h := lerp(h0, h1, f)
+	h -= math.Floor(h)
+
+	return fromHSV(h, lerp(s0, s1, f), lerp(v0, v1, f))
+}
+
+// toHSV returns hue, saturation, and value, each in [0, 1].
+func toHSV(c RGB) (float64, float64, float64) {
+	hi := math.Max(c.R, math.Max(c.G, c.B))
+	lo := math.Min(c.R, math.Min(c.G, c.B))
+	delta := hi - lo
+
+	if hi <= 0.0 || delta <= 0.0 {
+		return 0.0, 0.0, hi
+	}
+
+	var h float64
+	switch hi {
+	case c.R:
+		h = (c.G - c.B) / delta
+	case c.G:
+		h = 2.0 + (c.B-c.R)/delta
+	default:
+		h = 4.0 + (c.R-c.G)/delta
+	}
+	h /= 6.0
+	h -= math.Floor(h)
+
+	return h, delta / hi, hi
+}
+
+func fromHSV(h, s, v float64) RGB {
+	h *= 6.0
+	sector := math.Floor(h)
+	f := h - sector
+
+	p := v * (1.0 - s)
+	q := v * (1.0 - s*f)
+	t := v * (1.0 - s*(1.0-f))
+
+	switch int(sector) % 6 {
+	case 0:
+		return RGB{R: v, G: t, B: p}
+	case 1:
+		return RGB{R: q, G: v, B: p}
+	case 2:
+		return RGB{R: p, G: v, B: t}
+	case 3:
+		return RGB{R: p, G: q, B: v}
+	case 4:
+		return RGB{R: t, G: p, B: v}
+	default:
+		return RGB{R: v, G: p, B: q}
+	}
+}
+
+// ReadGGR reads a gradient in GIMP's .ggr format.
+func ReadGGR(r io.Reader) (GIMPGradient, error) {
+	scanner := bufio.NewScanner(r)
+
+	line := 0
+	next := func() (string, bool) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text != "" {
+				return text, true
+			}
+		}
+		return "", false
+	}
+
+	header, ok := next()
+	if !ok || header != "GIMP Gradient" {
+		return GIMPGradient{}, fmt.Errorf("line %d: missing \"GIMP Gradient\" header", line)
+	}
+
+	var result GIMPGradient
+
+	text, ok := next()
+	if ok && strings.HasPrefix(text, "Name:") {
+		result.Name = strings.TrimSpace(strings.TrimPrefix(text, "Name:"))
+		text, ok = next()
+	}
+	if !ok {
+		return GIMPGradient{}, fmt.Errorf("line %d: missing segment count", line)
+	}
+
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return GIMPGradient{}, fmt.Errorf("line %d: parsing segment count: %w", line, err)
+	}
+	if n <= 0 {
+		return GIMPGradient{}, fmt.Errorf("line %d: got %d segments, want at least 1", line, n)
+	}
+
+	for i := 0; i < n; i++ {
+		text, ok = next()
+		if !ok {
+			return GIMPGradient{}, fmt.Errorf("line %d: expected %d segments, got %d", line, n, i)
+		}
+
+		segment, err := parseSegment(text)
+		if err != nil {
+			return GIMPGradient{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		result.Segments = append(result.Segments, segment)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return GIMPGradient{}, err
+	}
+
+	return result, nil
+}
+
+func parseSegment(text string) (GIMPSegment, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 13 {
+		return GIMPSegment{}, fmt.Errorf("segment has %d fields, want at least 13", len(fields))
+	}
+
+	values := make([]float64, 13)
+	for i, field := range fields[:13] {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return GIMPSegment{}, fmt.Errorf("parsing segment field %d: %w", i, err)
+		}
+		values[i] = v
+	}
+
+	// Alpha, in values[6] and values[10], is ignored since palettes are opaque.
+	return GIMPSegment{
+		Left:       values[0],
+		Middle:     values[1],
+		Right:      values[2],
+		LeftColor:  RGB{R: values[3], G: values[4], B: values[5]},
+		RightColor: RGB{R: values[7], G: values[8], B: values[9]},
+		Blend:      int(values[11]),
+		Coloring:   int(values[12]),
+	}, nil
+}
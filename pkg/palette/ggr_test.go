@@ -0,0 +1,82 @@
+package palette
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad_GGR(t *testing.T) {
+	p, err := Load("testdata/fixture.ggr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := GIMPGradient{
+		Name: "Fixture",
+		Segments: []GIMPSegment{
+			{Left: 0, Middle: 0.25, Right: 0.5, LeftColor: RGB{}, RightColor: RGB{R: 1, G: 1, B: 1}},
+			{Left: 0.5, Middle: 0.75, Right: 1, LeftColor: RGB{R: 1}, RightColor: RGB{B: 1}, Coloring: 1},
+		},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+
+	tcs := []struct {
+		t    float64
+		want RGB
+	}{
+		{t: 0, want: RGB{}},
+		// The middle of the first segment is moved to 0.25.
+		{t: 0.25, want: gray(0.5)},
+		{t: 0.5, want: RGB{R: 1, G: 1, B: 1}},
+		// Counter-clockwise from red to blue passes through green.
+		{t: 0.75, want: RGB{G: 1}},
+		{t: 1, want: RGB{B: 1}},
+	}
+
+	for _, tc := range tcs {
+		if got := p.At(tc.t); got != tc.want.RGBA64() {
+			t.Errorf("At(%v) = %v, want %v", tc.t, got, tc.want.RGBA64())
+		}
+	}
+}
+
+func TestGIMPSegment_HSV(t *testing.T) {
+	s := GIMPSegment{Left: 0, Middle: 0.5, Right: 1, LeftColor: RGB{R: 1}, RightColor: RGB{B: 1}, Coloring: 2}
+
+	// Clockwise from red to blue passes through magenta.
+	if got, want := s.at(0.5), (RGB{R: 1, B: 1}); !closeRGB(got, want, 1e-9) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReadGGR_Invalid(t *testing.T) {
+	tcs := []struct {
+		name     string
+		text     string
+		wantLine string
+	}{
+		{name: "no header", text: "Name: x\n1\n", wantLine: "line 1"},
+		{name: "no count", text: "GIMP Gradient\nName: x\n", wantLine: "line 2"},
+		{name: "bad count", text: "GIMP Gradient\nName: x\nmany\n", wantLine: "line 3"},
+		{name: "no segments", text: "GIMP Gradient\nName: x\n0\n", wantLine: "line 3"},
+		{name: "negative count", text: "GIMP Gradient\n\n-2\n", wantLine: "line 3"},
+		{name: "missing segment", text: "GIMP Gradient\nName: x\n2\n0 0.5 1 0 0 0 1 1 1 1 1 0 0\n", wantLine: "line 4"},
+		{name: "short segment", text: "GIMP Gradient\nName: x\n1\n0 0.5 1 0 0 0 1\n", wantLine: "line 4"},
+		{name: "bad field", text: "GIMP Gradient\n1\n0 0.5 1 0 0 0 1 1 1 x 1 0 0\n", wantLine: "line 3"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadGGR(strings.NewReader(tc.text))
+			if err == nil {
+				t.Fatal("got nil error, want error")
+			}
+			if !strings.Contains(err.Error(), tc.wantLine) {
+				t.Errorf("got error %q, want it on %s", err, tc.wantLine)
+			}
+		})
+	}
+}
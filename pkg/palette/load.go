@@ -0,0 +1,76 @@
+package palette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Usage describes the values accepted by Parse.
+var Usage = fmt.Sprintf("palette name (%s) or path to a .json or .ggr gradient file",
+	strings.Join(BuiltinNames(), ", "))
+
+// Parse returns the Builtin palette with the passed name, or else loads the file at that path.
+func Parse(spec string) (Palette, error) {
+	if p, ok := Builtin[spec]; ok {
+		return p, nil
+	}
+
+	if filepath.Ext(spec) == "" {
+		return nil, fmt.Errorf("unknown palette %q; want one of %s or a file path",
+			spec, strings.Join(BuiltinNames(), ", "))
+	}
+
+	return Load(spec)
+}
+
+// Load reads a gradient file, choosing the format from the file's extension.
+func Load(path string) (Palette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		g, err := ReadJSON(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		return g, nil
+	case ".ggr":
+		g, err := ReadGGR(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		return g, nil
+	default:
+		return nil, fmt.Errorf("unknown palette format %q", filepath.Ext(path))
+	}
+}
+
+// ReadJSON reads a Gradient written as JSON, for example:
+//
+//	{"space": "oklab", "stops": [{"position": 0, "color": "#000000"}, {"position": 1, "color": "#ffffff"}]}
+func ReadJSON(r io.Reader) (Gradient, error) {
+	var g Gradient
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&g)
+	if err != nil {
+		return Gradient{}, err
+	}
+
+	err = g.Validate()
+	if err != nil {
+		return Gradient{}, err
+	}
+
+	return g, nil
+}
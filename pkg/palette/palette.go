@@ -0,0 +1,176 @@
+package palette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Palette maps a brightness in [0, 1] to a colour.
+// Brightnesses outside [0, 1] are clamped.
+type Palette interface {
+	At(t float64) color.RGBA64
+}
+
+// Render colours a row-major grid of brightnesses.
+func Render(p Palette, values []float64, width, height int) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for i, v := range values {
+		img.SetRGBA64(i%width, i/width, p.At(v))
+	}
+
+	return img
+}
+
+// Mix colours row-major grids of brightnesses, one per colour, by adding the colours
+// weighted by their brightnesses at each pixel. Sums are clamped to [0, 1].
+func Mix(colors []RGB, channels [][]float64, width, height int) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		sum := RGB{}
+		for c, values := range channels {
+			v := clamp(values[i])
+			sum.R += v * colors[c].R
+			sum.G += v * colors[c].G
+			sum.B += v * colors[c].B
+		}
+		img.SetRGBA64(i%width, i/width, sum.RGBA64())
+	}
+
+	return img
+}
+
+// RGB is an sRGB colour with components in [0, 1].
+//
+// In JSON it is written as a hex string such as "#ff8000".
+type RGB struct {
+	R, G, B float64
+}
+
+// ParseHex parses a colour written as "#rrggbb".
+func ParseHex(s string) (RGB, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return RGB{}, fmt.Errorf("colour %q is not of the form #rrggbb", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("parsing colour %q: %w", s, err)
+	}
+
+	return RGB{
+		R: float64(v>>16&0xff) / 0xff,
+		G: float64(v>>8&0xff) / 0xff,
+		B: float64(v&0xff) / 0xff,
+	}, nil
+}
+
+func mustHex(s string) RGB {
+	c, err := ParseHex(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", to8(c.R), to8(c.G), to8(c.B))
+}
+
+func (c RGB) MarshalText() ([]byte, error) {
+	return []byte(c.Hex()), nil
+}
+
+func (c *RGB) UnmarshalText(text []byte) error {
+	parsed, err := ParseHex(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = parsed
+	return nil
+}
+
+// RGBA64 converts c to an opaque colour.
+func (c RGB) RGBA64() color.RGBA64 {
+	return color.RGBA64{R: to16(c.R), G: to16(c.G), B: to16(c.B), A: 0xffff}
+}
+
+func to8(v float64) uint8 {
+	return uint8(math.Round(clamp(v) * 0xff))
+}
+
+func to16(v float64) uint16 {
+	return uint16(math.Round(clamp(v) * 0xffff))
+}
+
+func clamp(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0.0
+	}
+	return math.Min(math.Max(v, 0.0), 1.0)
+}
+
+// A Stop fixes the colour of a Gradient at a position in [0, 1].
+type Stop struct {
+	Position float64 `json:"position"`
+	Color    RGB     `json:"color"`
+}
+
+// A Gradient interpolates between colour Stops.
+type Gradient struct {
+	// Space is the colour space interpolation happens in. Empty means OKLab.
+	Space Space `json:"space,omitempty"`
+
+	// Stops must be sorted by Position.
+	Stops []Stop `json:"stops"`
+}
+
+var _ Palette = Gradient{}
+
+func (g Gradient) At(t float64) color.RGBA64 {
+	if len(g.Stops) == 0 {
+		return color.RGBA64{A: 0xffff}
+	}
+
+	t = clamp(t)
+
+	// The index of the first stop past t.
+	i := sort.Search(len(g.Stops), func(i int) bool {
+		return g.Stops[i].Position > t
+	})
+	if i == 0 {
+		return g.Stops[0].Color.RGBA64()
+	}
+	if i == len(g.Stops) {
+		return g.Stops[len(g.Stops)-1].Color.RGBA64()
+	}
+
+	from, to := g.Stops[i-1], g.Stops[i]
+	f := (t - from.Position) / (to.Position - from.Position)
+
+	return g.Space.interpolate(from.Color, to.Color, f).RGBA64()
+}
+
+// Validate returns an error if g cannot be used as a Palette.
+func (g Gradient) Validate() error {
+	if len(g.Stops) == 0 {
+		return fmt.Errorf("gradient has no stops")
+	}
+
+	for i, stop := range g.Stops {
+		if stop.Position < 0.0 || stop.Position > 1.0 {
+			return fmt.Errorf("stop %d has position %v outside [0, 1]", i, stop.Position)
+		}
+		if i > 0 && stop.Position < g.Stops[i-1].Position {
+			return fmt.Errorf("stop %d at %v is before the previous stop at %v", i, stop.Position, g.Stops[i-1].Position)
+		}
+	}
+
+	return g.Space.validate()
+}
@@ -0,0 +1,102 @@
+package palette
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Space is a colour space to interpolate between colours in.
+type Space string
+
+const (
+	// OKLab is perceptually uniform, so equal steps in a gradient look like equal steps in colour.
+	OKLab Space = "oklab"
+
+	// SRGB interpolates the gamma-encoded components directly.
+	SRGB Space = "srgb"
+
+	// LinearRGB interpolates light intensities, which mixes colours the way light does.
+	LinearRGB Space = "linear"
+)
+
+func (s Space) validate() error {
+	switch s {
+	case "", OKLab, SRGB, LinearRGB:
+		return nil
+	default:
+		return fmt.Errorf("unknown colour space %q", s)
+	}
+}
+
+func (s Space) interpolate(from, to RGB, f float64) RGB {
+	switch s {
+	case SRGB:
+		return lerpRGB(from, to, f)
+	case LinearRGB:
+		return fromLinear(lerpRGB(toLinear(from), toLinear(to), f))
+	default:
+		l0, a0, b0 := toOKLab(from)
+		l1, a1, b1 := toOKLab(to)
+
+		return fromOKLab(lerp(l0, l1, f), lerp(a0, a1, f), lerp(b0, b1, f))
+	}
+}
+
+func lerp(a, b, f float64) float64 {
+	return a + (b-a)*f
+}
+
+func lerpRGB(from, to RGB, f float64) RGB {
+	return RGB{R: lerp(from.R, to.R, f), G: lerp(from.G, to.G, f), B: lerp(from.B, to.B, f)}
+}
+
+func toLinear(c RGB) RGB {
+	return RGB{R: decode(c.R), G: decode(c.G), B: decode(c.B)}
+}
+
+func fromLinear(c RGB) RGB {
+	return RGB{R: encode(c.R), G: encode(c.G), B: encode(c.B)}
+}
+
+// decode removes the sRGB transfer function.
+func decode(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// encode applies the sRGB transfer function.
+func encode(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+}
+
+// toOKLab and fromOKLab use the matrices from https://bottosson.github.io/posts/oklab/.
+func toOKLab(c RGB) (float64, float64, float64) {
+	lin := toLinear(c)
+
+	l := math.Cbrt(0.4122214708*lin.R + 0.5363325363*lin.G + 0.0514459929*lin.B)
+	m := math.Cbrt(0.2119034982*lin.R + 0.6806995451*lin.G + 0.1073969566*lin.B)
+	s := math.Cbrt(0.0883024619*lin.R + 0.2817188376*lin.G + 0.6299787005*lin.B)
+
+	return 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s
+}
+
+func fromOKLab(lightness, a, b float64) RGB {
+	l := lightness + 0.3963377774*a + 0.2158037573*b
+	m := lightness - 0.1055613458*a - 0.0638541728*b
+	s := lightness - 0.0894841775*a - 1.2914855480*b
+
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return fromLinear(RGB{
+		R: 4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		G: -1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		B: -0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	})
+}
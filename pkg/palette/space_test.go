@@ -0,0 +1,80 @@
+package palette
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToOKLab(t *testing.T) {
+	tcs := []struct {
+		name    string
+		c       RGB
+		l, a, b float64
+	}{
+		{name: "black", c: RGB{}},
+		{name: "white", c: RGB{R: 1, G: 1, B: 1}, l: 1},
+		// Reference values from https://bottosson.github.io/posts/oklab/.
+		{name: "red", c: RGB{R: 1}, l: 0.627955, a: 0.224863, b: 0.125846},
+		{name: "green", c: RGB{G: 1}, l: 0.866440, a: -0.233888, b: 0.179498},
+		{name: "blue", c: RGB{B: 1}, l: 0.452014, a: -0.032457, b: -0.311528},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			l, a, b := toOKLab(tc.c)
+			if math.Abs(l-tc.l) > 1e-5 || math.Abs(a-tc.a) > 1e-5 || math.Abs(b-tc.b) > 1e-5 {
+				t.Errorf("got (%v, %v, %v), want (%v, %v, %v)", l, a, b, tc.l, tc.a, tc.b)
+			}
+
+			// The published matrices are only inverses to about six digits.
+			if got := fromOKLab(l, a, b); !closeRGB(got, tc.c, 1e-5) {
+				t.Errorf("fromOKLab(toOKLab(%v)) = %v", tc.c, got)
+			}
+		})
+	}
+}
+
+func TestSpace_Interpolate(t *testing.T) {
+	black, white := RGB{}, RGB{R: 1, G: 1, B: 1}
+
+	tcs := []struct {
+		name     string
+		space    Space
+		from, to RGB
+		middle   RGB
+	}{
+		// Half the lightness of white in OKLab is an eighth of its intensity.
+		{name: "oklab", space: OKLab, from: black, to: white, middle: gray(encode(0.125))},
+		{name: "default", space: "", from: black, to: white, middle: gray(encode(0.125))},
+		{name: "srgb", space: SRGB, from: black, to: white, middle: gray(0.5)},
+		{name: "linear", space: LinearRGB, from: black, to: white, middle: gray(encode(0.5))},
+		{name: "oklab colors", space: OKLab, from: RGB{R: 1}, to: RGB{G: 0.5, B: 1}},
+		{name: "linear colors", space: LinearRGB, from: RGB{R: 0.2, G: 0.9}, to: RGB{R: 0.7, B: 0.4}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.space.interpolate(tc.from, tc.to, 0); !closeRGB(got, tc.from, 1e-5) {
+				t.Errorf("got %v at the start, want %v", got, tc.from)
+			}
+			if got := tc.space.interpolate(tc.from, tc.to, 1); !closeRGB(got, tc.to, 1e-5) {
+				t.Errorf("got %v at the end, want %v", got, tc.to)
+			}
+
+			if tc.middle == (RGB{}) {
+				return
+			}
+			if got := tc.space.interpolate(tc.from, tc.to, 0.5); !closeRGB(got, tc.middle, 1e-5) {
+				t.Errorf("got %v in the middle, want %v", got, tc.middle)
+			}
+		})
+	}
+}
+
+func gray(v float64) RGB {
+	return RGB{R: v, G: v, B: v}
+}
+
+func closeRGB(a, b RGB, tolerance float64) bool {
+	return math.Abs(a.R-b.R) <= tolerance && math.Abs(a.G-b.G) <= tolerance && math.Abs(a.B-b.B) <= tolerance
+}
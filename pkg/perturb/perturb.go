@@ -0,0 +1,224 @@
+// Package perturb iterates z^2 + c at zooms too deep for complex128, by perturbation
+// around a single reference orbit computed at high precision.
+//
+// A point near the reference is iterated as its difference dz from the reference orbit,
+// which stays small enough to keep its precision as a float64:
+//
+//	dz' = (2Z + dz) dz + dc
+//
+// where Z is the reference orbit and dc is the difference between the point's and the
+// reference's parameter. Neither Z nor dz need more than float64 precision; only the
+// reference's starting point and parameter do.
+package perturb
+
+import (
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/escapetime"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strings"
+)
+
+// Precision returns the bits of precision needed to resolve points pixelSize apart
+// near a center of magnitude at most about one, with a margin for rounding.
+func Precision(pixelSize float64) uint {
+	return uint(math.Max(0.0, -math.Log2(pixelSize))) + 64
+}
+
+// A Complex is a complex number with arbitrary-precision parts.
+type Complex struct {
+	Re, Im *big.Float
+}
+
+// NewComplex returns z with prec bits of precision.
+func NewComplex(z complex128, prec uint) Complex {
+	return Complex{
+		Re: new(big.Float).SetPrec(prec).SetFloat64(real(z)),
+		Im: new(big.Float).SetPrec(prec).SetFloat64(imag(z)),
+	}
+}
+
+// ParseComplex parses "real,imaginary" in decimal with prec bits of precision.
+func ParseComplex(s string, prec uint) (Complex, error) {
+	re, im, ok := strings.Cut(s, ",")
+	if !ok {
+		return Complex{}, fmt.Errorf("parsing %q: want real,imaginary", s)
+	}
+
+	result := Complex{Re: new(big.Float).SetPrec(prec), Im: new(big.Float).SetPrec(prec)}
+
+	_, ok = result.Re.SetString(strings.TrimSpace(re))
+	if !ok {
+		return Complex{}, fmt.Errorf("parsing real part of %q", s)
+	}
+
+	_, ok = result.Im.SetString(strings.TrimSpace(im))
+	if !ok {
+		return Complex{}, fmt.Errorf("parsing imaginary part of %q", s)
+	}
+
+	return result, nil
+}
+
+// Add returns c + z.
+func (c Complex) Add(z complex128) Complex {
+	result := NewComplex(z, c.Re.Prec())
+	result.Re.Add(result.Re, c.Re)
+	result.Im.Add(result.Im, c.Im)
+	return result
+}
+
+// Value returns the nearest complex128 to c.
+func (c Complex) Value() complex128 {
+	re, _ := c.Re.Float64()
+	im, _ := c.Im.Float64()
+	return complex(re, im)
+}
+
+// A Reference is an orbit of z^2 + c which nearby orbits are iterated relative to.
+type Reference struct {
+	// Orbit is the reference orbit, rounded to complex128.
+	// It ends when the orbit escapes or reaches the maximum number of iterations.
+	Orbit []complex128
+
+	// C is the parameter of the reference orbit, rounded to complex128.
+	C complex128
+
+	// Parameter is whether orbits differ from the reference in c, as in the Mandelbrot
+	// set, rather than in their starting point, as in Julia sets.
+	Parameter bool
+}
+
+// Mandelbrot returns the reference orbit of the critical point 0 of z^2 + c.
+func Mandelbrot(c Complex, bailout float64, maxIterations int) *Reference {
+	return &Reference{
+		Orbit:     orbit(NewComplex(0, c.Re.Prec()), c, bailout, maxIterations),
+		C:         c.Value(),
+		Parameter: true,
+	}
+}
+
+// Julia returns the reference orbit of z^2 + c from z.
+func Julia(z Complex, c complex128, bailout float64, maxIterations int) *Reference {
+	return &Reference{
+		Orbit: orbit(z, NewComplex(c, z.Re.Prec()), bailout, maxIterations),
+		C:     c,
+	}
+}
+
+// orbit iterates z^2 + c from z at the precision of z.
+func orbit(z, c Complex, bailout float64, maxIterations int) []complex128 {
+	prec := z.Re.Prec()
+	x := new(big.Float).SetPrec(prec).Set(z.Re)
+	y := new(big.Float).SetPrec(prec).Set(z.Im)
+	xx := new(big.Float).SetPrec(prec)
+	yy := new(big.Float).SetPrec(prec)
+	xy := new(big.Float).SetPrec(prec)
+
+	// Escape needs at least one step of the reference to perturb.
+	result := []complex128{z.Value()}
+	for len(result) < 2 || len(result) <= maxIterations && cmplx.Abs(result[len(result)-1]) < bailout {
+		// (x + iy)^2 + c = x^2 - y^2 + c.re + i(2xy + c.im)
+		xx.Mul(x, x)
+		yy.Mul(y, y)
+		xy.Mul(x, y)
+
+		x.Sub(xx, yy)
+		x.Add(x, c.Re)
+		y.Add(xy, xy)
+		y.Add(y, c.Im)
+
+		xf, _ := x.Float64()
+		yf, _ := y.Float64()
+		result = append(result, complex(xf, yf))
+	}
+
+	return result
+}
+
+// An Orbit is the fate of an orbit iterated by Escape.
+type Orbit struct {
+	// Z is the last point of the orbit.
+	Z complex128
+
+	// Derivative is the derivative of Z with respect to the offset of the orbit
+	// from the reference.
+	Derivative complex128
+
+	// N is the number of iterations.
+	N int
+
+	// Escaped is whether the orbit reached the bailout.
+	Escaped bool
+}
+
+// Escape iterates the orbit offset from r by offset, in the starting point or the
+// parameter as r.Parameter says, until it reaches bailout or maxIterations, or falls
+// into an attracting cycle to within tolerance. A tolerance of zero disables cycle detection.
+//
+// Perturbation glitches where the orbit passes closer to zero than to the reference,
+// as the delta then carries too little precision relative to the orbit. These are
+// detected by |Z + dz| < |dz| and corrected by rebasing: restarting the reference from
+// its first point, with the delta taken relative to that. Orbits outliving the
+// reference are rebased likewise, so every orbit is followed to the end.
+func (r *Reference) Escape(offset complex128, bailout float64, maxIterations int, tolerance float64) Orbit {
+	var dz, dc, derivative complex128
+	if r.Parameter {
+		dc = offset
+	} else {
+		dz = offset
+		derivative = 1
+	}
+
+	result := Orbit{}
+	z := r.Orbit[0] + dz
+	cycles := escapetime.NewCycleDetector(z, tolerance)
+	m := 0
+	for result.N < maxIterations && cmplx.Abs(z) < bailout {
+		derivative = 2 * z * derivative
+		if r.Parameter {
+			derivative++
+		}
+
+		dz = (2*r.Orbit[m]+dz)*dz + dc
+		m++
+		z = r.Orbit[m] + dz
+		result.N++
+
+		if m == len(r.Orbit)-1 || cmplx.Abs(z) < cmplx.Abs(dz) {
+			dz = z - r.Orbit[0]
+			m = 0
+		}
+
+		if tolerance > 0.0 {
+			if period := cycles.Check(z); period > 0 {
+				if attracting(z, r.C+dc, period) {
+					// Periodic orbits never escape.
+					result.N = maxIterations
+				}
+				// Only look for a repelling cycle's neighbors again after a while.
+				cycles = escapetime.NewCycleDetector(z, tolerance)
+			}
+		}
+	}
+
+	result.Z = z
+	result.Derivative = derivative
+	result.Escaped = result.N < maxIterations
+	return result
+}
+
+// attracting reports whether z is on an attracting cycle of z^2 + c of the given period.
+//
+// Deep zooms are full of orbits which shadow repelling cycles for many iterations
+// to within any fixed tolerance before escaping, so only attracting cycles end orbits.
+func attracting(z, c complex128, period int) bool {
+	multiplier := complex(1, 0)
+	for i := 0; i < period; i++ {
+		multiplier *= 2 * z
+		z = z*z + c
+	}
+
+	return cmplx.Abs(multiplier) < 1.0
+}
@@ -0,0 +1,161 @@
+package perturb
+
+import (
+	"math/big"
+	"math/cmplx"
+	"testing"
+)
+
+// direct iterates z^2 + c from z in complex128, as Escape does by perturbation.
+func direct(z, c complex128, bailout float64, maxIterations int) Orbit {
+	result := Orbit{}
+	for result.N < maxIterations && cmplx.Abs(z) < bailout {
+		z = z*z + c
+		result.N++
+	}
+
+	result.Z = z
+	result.Escaped = result.N < maxIterations
+	return result
+}
+
+// checkOrbit compares an orbit found by perturbation with one iterated directly.
+func checkOrbit(t *testing.T, got, want Orbit) {
+	t.Helper()
+
+	if got.N != want.N || got.Escaped != want.Escaped {
+		t.Fatalf("got %d iterations, escaped %t; want %d, %t", got.N, got.Escaped, want.N, want.Escaped)
+	}
+	if cmplx.Abs(got.Z-want.Z) > 1e-9*cmplx.Abs(want.Z)+1e-12 {
+		t.Errorf("got last point %v, want %v", got.Z, want.Z)
+	}
+}
+
+func TestReference_Escape_Mandelbrot(t *testing.T) {
+	center := complex(-0.75, 0.1)
+	ref := Mandelbrot(NewComplex(center, Precision(1e-3)), 4.0, 200)
+
+	for _, offset := range []complex128{0, 1e-3, -2e-3i, 0.01 + 0.01i, 0.05 - 0.02i, 0.3 + 0.4i} {
+		got := ref.Escape(offset, 4.0, 200, 0.0)
+		want := direct(0, center+offset, 4.0, 200)
+		checkOrbit(t, got, want)
+	}
+}
+
+func TestReference_Escape_Julia(t *testing.T) {
+	c := complex(-0.4, 0.6)
+	ref := Julia(NewComplex(0.1+0.1i, Precision(1e-3)), c, 4.0, 100)
+
+	for _, offset := range []complex128{0, 1e-4, 1e-3i, -0.02 + 0.01i} {
+		got := ref.Escape(offset, 4.0, 100, 0.0)
+		want := direct(0.1+0.1i+offset, c, 4.0, 100)
+		checkOrbit(t, got, want)
+	}
+}
+
+func TestReference_Escape_Rebase(t *testing.T) {
+	// The reference escapes within a few iterations, so orbits of points in the set
+	// outlive it and must be rebased onto its start again and again.
+	center := complex(0.5, 0.0)
+	ref := Mandelbrot(NewComplex(center, Precision(1e-3)), 4.0, 100)
+	if len(ref.Orbit) > 10 {
+		t.Fatalf("reference orbit has %d points, want it to escape quickly", len(ref.Orbit))
+	}
+
+	for _, c := range []complex128{-0.1, -1.0, -0.1 + 0.2i, 0.2} {
+		got := ref.Escape(c-center, 4.0, 100, 0.0)
+		want := direct(0, c, 4.0, 100)
+		checkOrbit(t, got, want)
+	}
+}
+
+func TestReference_Escape_Glitch(t *testing.T) {
+	// The orbit of -1 passes through zero, so points near it are closer to zero than
+	// to the reference there.
+	center := complex(-1.0, 0.0)
+	ref := Mandelbrot(NewComplex(center, Precision(1e-3)), 4.0, 100)
+
+	for _, offset := range []complex128{0.5, -0.3 + 0.1i, 0.2i} {
+		got := ref.Escape(offset, 4.0, 100, 0.0)
+		want := direct(0, center+offset, 4.0, 100)
+		checkOrbit(t, got, want)
+	}
+}
+
+func TestReference_Escape_Cycles(t *testing.T) {
+	// With cycle detection, an orbit attracted to a cycle ends as if it never escaped.
+	ref := Mandelbrot(NewComplex(-1.0, Precision(1e-3)), 4.0, 10000)
+
+	got := ref.Escape(0.01, 4.0, 10000, 1e-12)
+	if got.Escaped || got.N != 10000 {
+		t.Errorf("got %d iterations, escaped %t; want 10000, false", got.N, got.Escaped)
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	tcs := []struct {
+		pixelSize float64
+		want      uint
+	}{
+		{pixelSize: 1.0, want: 64},
+		{pixelSize: 4.0, want: 64},
+		{pixelSize: 0x1p-10, want: 74},
+		{pixelSize: 0x1p-100, want: 164},
+	}
+
+	for _, tc := range tcs {
+		if got := Precision(tc.pixelSize); got != tc.want {
+			t.Errorf("Precision(%v) = %d, want %d", tc.pixelSize, got, tc.want)
+		}
+	}
+}
+
+func TestParseComplex(t *testing.T) {
+	tcs := []struct {
+		name    string
+		s       string
+		want    complex128
+		wantErr bool
+	}{
+		{name: "simple", s: "0.5,-1", want: complex(0.5, -1)},
+		{name: "spaces", s: " -0.75 , 1e-3 ", want: complex(-0.75, 1e-3)},
+		{name: "no comma", s: "0.5", wantErr: true},
+		{name: "empty", s: "", wantErr: true},
+		{name: "bad real", s: "x,1", wantErr: true},
+		{name: "bad imaginary", s: "1,", wantErr: true},
+		{name: "too many parts", s: "1,2,3", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseComplex(tc.s, 128)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", got.Value())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Value() != tc.want {
+				t.Errorf("got %v, want %v", got.Value(), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseComplex_Precision(t *testing.T) {
+	// Differs from 0.1 beyond the precision of a float64.
+	got, err := ParseComplex("0.1000000000000000000000000001,0", 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenth := new(big.Float).SetPrec(200)
+	tenth.SetString("0.1")
+	if got.Re.Cmp(tenth) != 1 {
+		t.Errorf("got real part %v, want more than 0.1", got.Re.Text('g', 40))
+	}
+}
@@ -0,0 +1,107 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Reporter periodically writes how much of a render is done, its throughput,
+// and the estimated time remaining.
+//
+// Add may be called concurrently from any number of workers.
+type Reporter struct {
+	w     io.Writer
+	unit  string
+	total int64
+	start time.Time
+
+	done    atomic.Int64
+	skipped atomic.Int64
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// Start begins reporting progress toward total units of work to w every interval.
+// If interval is not positive, progress is only reported by Stop.
+func Start(w io.Writer, unit string, total int64, interval time.Duration) *Reporter {
+	r := &Reporter{
+		w:     w,
+		unit:  unit,
+		total: total,
+		start: time.Now(),
+		stop:  make(chan struct{}),
+	}
+
+	if interval <= 0 {
+		return r
+	}
+
+	r.stopped.Add(1)
+	go func() {
+		defer r.stopped.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.report()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+
+	return r
+}
+
+// Add records that n more units of work are done.
+func (r *Reporter) Add(n int64) {
+	r.done.Add(n)
+}
+
+// Skip records that n units of work were done before the Reporter started, such as
+// by a resumed render. Skipped work counts toward progress but not throughput.
+func (r *Reporter) Skip(n int64) {
+	r.done.Add(n)
+	r.skipped.Add(n)
+}
+
+// Done returns the number of units of work done so far.
+func (r *Reporter) Done() int64 {
+	return r.done.Load()
+}
+
+// Stop stops periodic reports and writes a final one.
+func (r *Reporter) Stop() {
+	close(r.stop)
+	r.stopped.Wait()
+	r.report()
+}
+
+func (r *Reporter) report() {
+	done := r.done.Load()
+	elapsed := time.Since(r.start)
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(done-r.skipped.Load()) / elapsed.Seconds()
+	}
+
+	eta := "unknown"
+	if done >= r.total {
+		eta = "done"
+	} else if rate > 0 {
+		remaining := time.Duration(float64(r.total-done) / rate * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	_, _ = fmt.Fprintf(r.w, "%d/%d %s (%.1f%%), %.4g %s/s, elapsed %s, ETA %s\n",
+		done, r.total, r.unit, 100*float64(done)/float64(max(r.total, 1)),
+		rate, r.unit, elapsed.Round(time.Second), eta)
+}
@@ -0,0 +1,66 @@
+package rng
+
+import (
+	"math/rand"
+	randv2 "math/rand/v2"
+)
+
+// New returns a generator for the stream identified by keys under seed.
+//
+// The same seed and keys always produce the same sequence, and distinct keys give
+// independent streams. Use the keys to name whatever the stream belongs to,
+// for example New(seed, worker) or New(seed, row).
+func New(seed int64, keys ...int) *rand.Rand {
+	return rand.New(NewSource(seed, keys...))
+}
+
+// NewSource returns the Source behind New.
+func NewSource(seed int64, keys ...int) *Source {
+	// Each key is mixed into a scrambled copy of the hash so far, rather than
+	// symmetrically with it, so that New(1, 1) and New(2, 0) differ.
+	h := splitMix64(uint64(seed))
+	for _, key := range keys {
+		h = splitMix64(splitMix64(h) ^ uint64(key))
+	}
+
+	return &Source{pcg: randv2.NewPCG(h, splitMix64(h))}
+}
+
+// splitMix64 is the finalizer of the SplitMix64 generator, which scrambles the
+// bits of x so that nearby seeds give unrelated states.
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Source is a PCG generator usable with math/rand, whose state can be saved and restored.
+type Source struct {
+	pcg *randv2.PCG
+}
+
+var _ rand.Source64 = (*Source)(nil)
+
+func (s *Source) Uint64() uint64 {
+	return s.pcg.Uint64()
+}
+
+func (s *Source) Int63() int64 {
+	return int64(s.pcg.Uint64() >> 1)
+}
+
+func (s *Source) Seed(seed int64) {
+	*s = *NewSource(seed)
+}
+
+func (s *Source) MarshalBinary() ([]byte, error) {
+	return s.pcg.MarshalBinary()
+}
+
+func (s *Source) UnmarshalBinary(data []byte) error {
+	if s.pcg == nil {
+		s.pcg = &randv2.PCG{}
+	}
+	return s.pcg.UnmarshalBinary(data)
+}
@@ -0,0 +1,104 @@
+package rng
+
+import (
+	"slices"
+	"testing"
+)
+
+// stream returns the first n values of the stream for seed and keys.
+func stream(seed int64, n int, keys ...int) []uint64 {
+	src := NewSource(seed, keys...)
+
+	result := make([]uint64, n)
+	for i := range result {
+		result[i] = src.Uint64()
+	}
+
+	return result
+}
+
+func TestNewSource_Reproducible(t *testing.T) {
+	tcs := []struct {
+		name string
+		seed int64
+		keys []int
+	}{
+		{name: "no keys", seed: 1},
+		{name: "worker", seed: 1, keys: []int{3}},
+		{name: "row", seed: -7, keys: []int{1439}},
+		{name: "nested", seed: 42, keys: []int{2, 5}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, want := stream(tc.seed, 100, tc.keys...), stream(tc.seed, 100, tc.keys...)
+			if !slices.Equal(got, want) {
+				t.Errorf("got different streams for the same seed and keys")
+			}
+		})
+	}
+}
+
+func TestNewSource_Distinct(t *testing.T) {
+	tcs := []struct {
+		name         string
+		seed1, seed2 int64
+		keys1, keys2 []int
+	}{
+		{name: "indices", seed1: 1, seed2: 1, keys1: []int{0}, keys2: []int{1}},
+		{name: "far indices", seed1: 1, seed2: 1, keys1: []int{7}, keys2: []int{1 << 20}},
+		{name: "seeds", seed1: 1, seed2: 2, keys1: []int{0}, keys2: []int{0}},
+		{name: "key or none", seed1: 1, seed2: 1, keys2: []int{0}},
+		{name: "key order", seed1: 1, seed2: 1, keys1: []int{1, 2}, keys2: []int{2, 1}},
+		// Keys aren't simply added to the seed.
+		{name: "seed and index", seed1: 1, seed2: 2, keys1: []int{1}, keys2: []int{0}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := stream(tc.seed1, 100, tc.keys1...), stream(tc.seed2, 100, tc.keys2...)
+
+			// Independent streams share a value by chance with probability about 2^-50.
+			for _, v := range a {
+				if slices.Contains(b, v) {
+					t.Fatalf("streams share the value %d", v)
+				}
+			}
+		})
+	}
+}
+
+func TestSource_MarshalBinary(t *testing.T) {
+	src := NewSource(5, 1)
+	for i := 0; i < 10; i++ {
+		src.Uint64()
+	}
+
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := &Source{}
+	err = restored.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if got, want := restored.Uint64(), src.Uint64(); got != want {
+			t.Fatalf("value %d after restoring is %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestSource_Seed(t *testing.T) {
+	src := NewSource(1, 2)
+	src.Seed(9)
+
+	for i, want := range stream(9, 100) {
+		if got := src.Uint64(); got != want {
+			t.Fatalf("value %d after Seed(9) is %d, want %d", i, got, want)
+		}
+	}
+}
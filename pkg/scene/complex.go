@@ -0,0 +1,50 @@
+package scene
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Complex is a complex number written in JSON as [real, imaginary].
+type Complex [2]float64
+
+// NewComplex converts z to a Complex.
+func NewComplex(z complex128) Complex {
+	return Complex{real(z), imag(z)}
+}
+
+// Value converts c to a complex128.
+func (c Complex) Value() complex128 {
+	return complex(c[0], c[1])
+}
+
+// String writes c as "real,imaginary".
+func (c Complex) String() string {
+	return strconv.FormatFloat(c[0], 'g', -1, 64) + "," + strconv.FormatFloat(c[1], 'g', -1, 64)
+}
+
+// Set parses "real,imaginary" or a plain real number, so Complex can be used as a flag.
+func (c *Complex) Set(s string) error {
+	re, im, hasIm := strings.Cut(s, ",")
+
+	r, err := strconv.ParseFloat(strings.TrimSpace(re), 64)
+	if err != nil {
+		return fmt.Errorf("parsing real part of %q: %w", s, err)
+	}
+
+	i := 0.0
+	if hasIm {
+		i, err = strconv.ParseFloat(strings.TrimSpace(im), 64)
+		if err != nil {
+			return fmt.Errorf("parsing imaginary part of %q: %w", s, err)
+		}
+	}
+
+	*c = Complex{r, i}
+	return nil
+}
+
+func (Complex) Type() string {
+	return "complex"
+}
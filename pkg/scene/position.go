@@ -0,0 +1,144 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// positionError annotates err with the file position it occurred at.
+//
+// encoding/json only reports offsets for some errors, so otherwise the error's
+// field path is looked up among the keys of the document.
+func positionError(path string, data []byte, err error) error {
+	offset := int64(-1)
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset is past the byte which caused the error.
+		offset = max(0, syntaxErr.Offset-1)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		offset = int64(len(data))
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			offset = keyOffsets(data)[typeErr.Field]
+		}
+		if offset <= 0 {
+			offset = typeErr.Offset
+		}
+	default:
+		// Unknown fields are reported as: json: unknown field "name"
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			offset = findKey(data, strings.Trim(name, `"`))
+		}
+	}
+
+	return positionAt(path, data, offset, err)
+}
+
+// positionAt annotates err with the line and column of offset in data.
+func positionAt(path string, data []byte, offset int64, err error) error {
+	if offset < 0 {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	offset = min(offset, int64(len(data)))
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+
+	return fmt.Errorf("%s:%d:%d: %w", path, line, column, err)
+}
+
+// keyOffsets returns the offset of every object key in data by its dotted path,
+// such as "fractal.params". Array indices are not part of paths, matching how
+// encoding/json reports fields. Only the first occurrence of a path is kept.
+func keyOffsets(data []byte) map[string]int64 {
+	result := make(map[string]int64)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	// stack holds, for each open container, whether it is an object.
+	var stack []bool
+	// keys holds the key of each open object, or "" for arrays.
+	var keys []string
+	expectKey := false
+
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return result
+		}
+
+		if expectKey {
+			if key, ok := token.(string); ok {
+				keys[len(keys)-1] = key
+				path := joinKeys(keys)
+				if _, seen := result[path]; !seen {
+					result[path] = skipSeparators(data, before)
+				}
+				expectKey = false
+				continue
+			}
+		}
+
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, true)
+			keys = append(keys, "")
+			expectKey = true
+			continue
+		case json.Delim('['):
+			stack = append(stack, false)
+			keys = append(keys, "")
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			keys = keys[:len(keys)-1]
+		}
+
+		// After a value inside an object, the next token is a key.
+		expectKey = len(stack) > 0 && stack[len(stack)-1]
+	}
+}
+
+func joinKeys(keys []string) string {
+	var parts []string
+	for _, key := range keys {
+		if key != "" {
+			parts = append(parts, key)
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// findKey returns the offset of the first key named name, or -1.
+func findKey(data []byte, name string) int64 {
+	offset := int64(-1)
+
+	for path, o := range keyOffsets(data) {
+		if path != name && !strings.HasSuffix(path, "."+name) {
+			continue
+		}
+		if offset < 0 || o < offset {
+			offset = o
+		}
+	}
+
+	return offset
+}
+
+// skipSeparators advances offset past whitespace, commas, and colons.
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+
+	return offset
+}
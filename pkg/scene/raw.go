@@ -0,0 +1,68 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/accum"
+	"os"
+	"path/filepath"
+)
+
+// WriteRaw saves buf to s.Output.Raw in the raw buffer format of package accum,
+// with s as the header. Does nothing if s.Output.Raw is empty.
+func (s *Scene) WriteRaw(buf *accum.Buffer) error {
+	path := s.Output.Raw
+	if path == "" {
+		return nil
+	}
+
+	header, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = buf.Write(f, header)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadRaw reads a buffer saved by WriteRaw along with the scene which rendered it.
+//
+// The scene's Params are decoded generically since the reader may not know their type.
+func ReadRaw(path string) (*Scene, *accum.Buffer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	buf, header, err := accum.Read(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	s := &Scene{}
+	err = json.Unmarshal(header, s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: decoding header: %w", path, err)
+	}
+	if s.Version != Version {
+		return nil, nil, fmt.Errorf("%s: unsupported scene version %d, want %d", path, s.Version, Version)
+	}
+
+	return s, buf, nil
+}
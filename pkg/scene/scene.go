@@ -0,0 +1,212 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/pflag"
+	"github.com/willbeason/tree-fractal/pkg/palette"
+	"github.com/willbeason/tree-fractal/pkg/tonemap"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Version is the version of the scene format this package reads and writes.
+const Version = 1
+
+// A Scene fully describes a render.
+//
+// Scenes are written as JSON. Fields missing from a file keep the defaults of
+// the command rendering it.
+type Scene struct {
+	// Version must be Version.
+	Version int `json:"version"`
+
+	Fractal Fractal `json:"fractal"`
+
+	Viewport viewport.Viewport `json:"viewport"`
+
+	Sampling Sampling `json:"sampling"`
+
+	// Coloring is what escape-time renderers measure at each sample, as accepted by escapetime.ParseColoring.
+	Coloring string `json:"coloring,omitempty"`
+
+	// Bailout is when escape-time renderers consider orbits escaped, as accepted by
+	// escapetime.ParseBailout. Empty means the largest radius the map can take.
+	Bailout string `json:"bailout,omitempty"`
+
+	// ToneMap is a chain of tone map operators in the syntax of tonemap.Parse.
+	ToneMap string `json:"tonemap,omitempty"`
+
+	// Palette is a palette name or gradient file path, as accepted by palette.Parse.
+	Palette string `json:"palette,omitempty"`
+
+	Output Output `json:"output"`
+}
+
+// Fractal identifies what to render.
+type Fractal struct {
+	// Kind is the command which renders the scene, such as "julia" or "tree".
+	Kind string `json:"kind"`
+
+	// Params are the Kind-specific parameters.
+	// Set Params to a pointer before loading to decode into it.
+	Params any `json:"params,omitempty"`
+}
+
+// Sampling controls how much work a render does.
+type Sampling struct {
+	// SubPixels is the number of samples taken per pixel.
+	SubPixels int `json:"subPixels,omitempty"`
+
+	// MaxIterations bounds the length of each orbit.
+	MaxIterations int `json:"maxIterations,omitempty"`
+
+	// PeriodTolerance is how close an orbit must return to an earlier point to be
+	// considered periodic, so that iterating it can stop early. Zero disables the check.
+	PeriodTolerance float64 `json:"periodTolerance,omitempty"`
+
+	// Samples is the total number of points plotted by Monte Carlo renderers.
+	Samples int64 `json:"samples,omitempty"`
+
+	// WarmUp is the number of steps taken before points are plotted.
+	WarmUp int `json:"warmUp,omitempty"`
+
+	// Splat is how samples are spread over pixels, as accepted by accum.ParseSplatter.
+	Splat string `json:"splat,omitempty"`
+
+	// Seed seeds the random number generators. Commands seed from the clock if a
+	// scene file doesn't set it, so it is always written, even if zero.
+	Seed int64 `json:"seed"`
+
+	// Workers is the number of worker goroutines. Zero means one per CPU.
+	Workers int `json:"workers,omitempty"`
+}
+
+// Output describes where a render is written.
+type Output struct {
+	// Path is the PNG file to write. If empty, a timestamped file in out/ is written.
+	Path string `json:"path,omitempty"`
+
+	// Raw, if set, is where the accumulation buffer is saved before tone mapping,
+	// so that it can be recolored without rendering again.
+	Raw string `json:"raw,omitempty"`
+}
+
+// AddFlags registers flags for the framing, tone map, and palette of s.
+// The current values of s are the defaults.
+func (s *Scene) AddFlags(flags *pflag.FlagSet) {
+	s.Viewport.AddFlags(flags)
+	flags.StringVar(&s.ToneMap, "tonemap", s.ToneMap, tonemap.Usage)
+	flags.StringVar(&s.Palette, "palette", s.Palette, palette.Usage)
+}
+
+// Apply loads the scene at path into s, then reapplies any flags set on the
+// command line so that they take precedence over the file.
+//
+// Does nothing if path is empty.
+func (s *Scene) Apply(path string, flags *pflag.FlagSet) error {
+	if path == "" {
+		return nil
+	}
+
+	set := make(map[string]string)
+	flags.Visit(func(f *pflag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	err := s.Load(path)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range set {
+		err = flags.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("reapplying --%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Load reads the scene file at path into s.
+//
+// Errors point to the line and column in the file which caused them.
+func (s *Scene) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	kind := s.Fractal.Kind
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(s)
+	if err != nil {
+		return positionError(path, data, err)
+	}
+
+	end := decoder.InputOffset()
+	if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+		return positionAt(path, data, skipSeparators(data, end), fmt.Errorf("unexpected data after scene"))
+	}
+
+	if s.Version != Version {
+		return fmt.Errorf("%s: unsupported scene version %d, want %d", path, s.Version, Version)
+	}
+	if kind != "" && s.Fractal.Kind != kind {
+		return fmt.Errorf("%s: scene is for %q, not %q", path, s.Fractal.Kind, kind)
+	}
+
+	return nil
+}
+
+// Resolve sets an empty Path to a timestamped file in out/, so that files related
+// to the output can be named after it.
+func (o *Output) Resolve() {
+	if o.Path == "" {
+		o.Path = filepath.Join("out", time.Now().Format("20060102150405")+".png")
+	}
+}
+
+// Linked returns the output of a render linked to o, named after o with suffix
+// added before the extension.
+func (o *Output) Linked(suffix string) Output {
+	o.Resolve()
+	ext := filepath.Ext(o.Path)
+	return Output{Path: strings.TrimSuffix(o.Path, ext) + suffix + ext}
+}
+
+// WritePNG encodes img to the output path, creating its directory if needed.
+func (o Output) WritePNG(img image.Image) error {
+	o.Resolve()
+	path := o.Path
+
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(f, img)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,134 @@
+package scene
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/willbeason/tree-fractal/pkg/viewport"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testParams struct {
+	N int `json:"n"`
+}
+
+func TestScene_Load_Errors(t *testing.T) {
+	tcs := []struct {
+		name string
+		kind string
+		json string
+		want string
+	}{
+		{
+			name: "syntax",
+			json: "{\n  \"version\": 1,\n  \"viewport\": {\"width\": }\n}",
+			want: "scene.json:3:25: invalid character '}'",
+		},
+		{
+			name: "truncated",
+			json: "{\n  \"version\": 1,\n",
+			want: "scene.json:3:1: unexpected EOF",
+		},
+		{
+			name: "type",
+			json: "{\n  \"version\": 1,\n  \"viewport\": {\n    \"width\": \"wide\"\n  }\n}",
+			want: "scene.json:4:5: json: cannot unmarshal string into Go struct field",
+		},
+		{
+			name: "params type",
+			json: "{\n  \"version\": 1,\n  \"fractal\": {\n    \"params\": {\"n\": 1.5}\n  }\n}",
+			want: "scene.json:4:16: json: cannot unmarshal number 1.5",
+		},
+		{
+			name: "unknown field",
+			json: "{\n  \"version\": 1,\n  \"colour\": \"red\"\n}",
+			want: "scene.json:3:3: json: unknown field \"colour\"",
+		},
+		{
+			name: "trailing data",
+			json: "{\"version\": 1}\n {}",
+			want: "scene.json:2:2: unexpected data after scene",
+		},
+		{
+			name: "version",
+			json: "{\"version\": 2}",
+			want: "scene.json: unsupported scene version 2",
+		},
+		{
+			name: "kind",
+			kind: "julia",
+			json: "{\"version\": 1, \"fractal\": {\"kind\": \"tree\"}}",
+			want: "scene.json: scene is for \"tree\", not \"julia\"",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scene.json")
+			err := os.WriteFile(path, []byte(tc.json), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := Scene{Fractal: Fractal{Kind: tc.kind, Params: &testParams{}}}
+			err = s.Load(path)
+			if err == nil {
+				t.Fatal("got nil error, want error")
+			}
+
+			got := strings.TrimPrefix(err.Error(), filepath.Dir(path)+string(filepath.Separator))
+			if !strings.HasPrefix(got, tc.want) {
+				t.Errorf("got error %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+var viewportDefaults = viewport.Viewport{Width: 10, Height: 10, CenterX: -0.5, ViewHeight: 1}
+
+func TestScene_Apply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scene.json")
+	err := os.WriteFile(path, []byte(`{
+  "version": 1,
+  "fractal": {"kind": "julia", "params": {"n": 3}},
+  "viewport": {"width": 50, "height": 60, "viewHeight": 2},
+  "tonemap": "gamma:0.5",
+  "palette": "fire"
+}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := &testParams{N: 2}
+	s := Scene{
+		Fractal:  Fractal{Kind: "julia", Params: params},
+		Viewport: viewportDefaults,
+		ToneMap:  "linear",
+		Palette:  "escape",
+	}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	s.AddFlags(flags)
+	err = flags.Parse([]string{"--view-height", "4", "--tonemap", "log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Apply(path, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags set on the command line win over the file, which wins over defaults.
+	if s.Viewport.ViewHeight != 4 || s.ToneMap != "log" {
+		t.Errorf("got view height %v and tone map %q, want the flags' 4 and \"log\"", s.Viewport.ViewHeight, s.ToneMap)
+	}
+	if s.Viewport.Width != 50 || s.Viewport.Height != 60 || s.Palette != "fire" || params.N != 3 {
+		t.Errorf("got %dx%d, palette %q, and n %d; want the file's 50x60, \"fire\", and 3",
+			s.Viewport.Width, s.Viewport.Height, s.Palette, params.N)
+	}
+	if s.Viewport.CenterX != viewportDefaults.CenterX {
+		t.Errorf("got center x %v, want the default %v", s.Viewport.CenterX, viewportDefaults.CenterX)
+	}
+}
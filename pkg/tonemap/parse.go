@@ -0,0 +1,98 @@
+package tonemap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Usage describes the syntax accepted by Parse.
+const Usage = `comma-separated tone map operators applied in order: ` +
+	`linear[:GAIN], gamma:GAMMA, log[:SCALE], equalize, clip:LOW:HIGH (percentiles)`
+
+// Parse parses a comma-separated chain of operators, such as "gamma:0.2,linear:2.5".
+func Parse(spec string) (Operator, error) {
+	var chain Chain
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		op, err := parseOperator(part)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, op)
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("empty tone map %q", spec)
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+
+	return chain, nil
+}
+
+func parseOperator(s string) (Operator, error) {
+	fields := strings.Split(s, ":")
+	name := fields[0]
+
+	args := make([]float64, len(fields)-1)
+	for i, field := range fields[1:] {
+		arg, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing argument %q of tone map %q: %w", field, name, err)
+		}
+		args[i] = arg
+	}
+
+	switch name {
+	case "linear":
+		gain, err := optionalArg(name, args, 1.0)
+		if err != nil {
+			return nil, err
+		}
+		return Linear{Gain: gain}, nil
+	case "gamma":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("tone map gamma takes 1 argument, got %d", len(args))
+		}
+		return Gamma{Gamma: args[0]}, nil
+	case "log":
+		scale, err := optionalArg(name, args, 1.0)
+		if err != nil {
+			return nil, err
+		}
+		if scale <= 0.0 {
+			return nil, fmt.Errorf("tone map log scale must be positive, got %v", scale)
+		}
+		return Log{Scale: scale}, nil
+	case "equalize":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("tone map equalize takes no arguments, got %d", len(args))
+		}
+		return Equalize{}, nil
+	case "clip":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("tone map clip takes 2 arguments, got %d", len(args))
+		}
+		return Clip{Low: args[0], High: args[1]}, nil
+	default:
+		return nil, fmt.Errorf("unknown tone map %q", name)
+	}
+}
+
+func optionalArg(name string, args []float64, def float64) (float64, error) {
+	switch len(args) {
+	case 0:
+		return def, nil
+	case 1:
+		return args[0], nil
+	default:
+		return 0.0, fmt.Errorf("tone map %s takes at most 1 argument, got %d", name, len(args))
+	}
+}
@@ -0,0 +1,49 @@
+package tonemap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		want    Operator
+		wantErr bool
+	}{
+		{spec: "linear", want: Linear{Gain: 1}},
+		{spec: "linear:2.5", want: Linear{Gain: 2.5}},
+		{spec: "gamma:0.2", want: Gamma{Gamma: 0.2}},
+		{spec: "log", want: Log{Scale: 1}},
+		{spec: "equalize", want: Equalize{}},
+		{spec: "clip:1:99.5", want: Clip{Low: 1, High: 99.5}},
+		{spec: "gamma:0.2, linear:1.25", want: Chain{Gamma{Gamma: 0.2}, Linear{Gain: 1.25}}},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+		{spec: "gamma", wantErr: true},
+		{spec: "gamma:x", wantErr: true},
+		{spec: "linear:1:2", wantErr: true},
+		{spec: "log:0", wantErr: true},
+		{spec: "equalize:1", wantErr: true},
+		{spec: "clip:1", wantErr: true},
+		{spec: "sqrt", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		got, err := Parse(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want error", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.spec, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tc.spec, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package tonemap
+
+import (
+	"math"
+	"sort"
+)
+
+// An Operator maps accumulated values to brightnesses in place.
+//
+// Operators which normalize produce brightnesses in [0, 1] unless noted, though
+// later operators in a Chain may push values outside that range.
+type Operator interface {
+	Apply(values []float64)
+}
+
+// Chain applies each Operator in order.
+type Chain []Operator
+
+var _ Operator = Chain{}
+
+func (c Chain) Apply(values []float64) {
+	for _, op := range c {
+		op.Apply(values)
+	}
+}
+
+// Linear divides by the maximum value, then multiplies by Gain.
+type Linear struct {
+	Gain float64
+}
+
+var _ Operator = Linear{}
+
+func (l Linear) Apply(values []float64) {
+	maxValue := maximum(values)
+	if maxValue <= 0.0 {
+		return
+	}
+
+	scale := l.Gain / maxValue
+	for i, v := range values {
+		values[i] = v * scale
+	}
+}
+
+// Gamma raises each non-negative value to the power Gamma. It does not normalize.
+type Gamma struct {
+	Gamma float64
+}
+
+var _ Operator = Gamma{}
+
+func (g Gamma) Apply(values []float64) {
+	for i, v := range values {
+		if v > 0.0 {
+			values[i] = math.Pow(v, g.Gamma)
+		}
+	}
+}
+
+// Log maps values to log(1 + Scale*v), normalized so the maximum value becomes 1.
+// Useful for densities which span many orders of magnitude.
+type Log struct {
+	Scale float64
+}
+
+var _ Operator = Log{}
+
+func (l Log) Apply(values []float64) {
+	maxValue := maximum(values)
+	if maxValue <= 0.0 {
+		return
+	}
+
+	norm := 1.0 / math.Log1p(l.Scale*maxValue)
+	for i, v := range values {
+		if v > 0.0 {
+			values[i] = math.Log1p(l.Scale*v) * norm
+		}
+	}
+}
+
+// Equalize replaces each value with its rank among the distinct values present,
+// divided by the number of distinct values.
+//
+// This spreads brightness evenly over every density level that occurs, no matter
+// how few pixels have it.
+type Equalize struct{}
+
+var _ Operator = Equalize{}
+
+func (Equalize) Apply(values []float64) {
+	levels := distinct(values)
+	if len(levels) == 0 {
+		return
+	}
+
+	invLevels := 1.0 / float64(len(levels))
+	for i, v := range values {
+		values[i] = float64(sort.SearchFloat64s(levels, v)) * invLevels
+	}
+}
+
+// Clip clamps values to the range between the Low and High percentiles, then
+// rescales that range to [0, 1].
+//
+// Percentiles are in [0, 100]. Clipping the top fraction of a percent keeps a few
+// extremely dense pixels from darkening the rest of the image.
+type Clip struct {
+	Low, High float64
+}
+
+var _ Operator = Clip{}
+
+func (c Clip) Apply(values []float64) {
+	if len(values) == 0 {
+		return
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	low := percentile(sorted, c.Low)
+	high := percentile(sorted, c.High)
+	if high <= low {
+		high = low + 1.0
+	}
+
+	invRange := 1.0 / (high - low)
+	for i, v := range values {
+		values[i] = (math.Min(math.Max(v, low), high) - low) * invRange
+	}
+}
+
+func maximum(values []float64) float64 {
+	result := 0.0
+	for _, v := range values {
+		result = math.Max(result, v)
+	}
+
+	return result
+}
+
+// distinct returns the sorted distinct values.
+func distinct(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var result []float64
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+// percentile returns the p-th percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	idx := int(math.Round(p / 100.0 * float64(len(sorted)-1)))
+	idx = max(0, min(idx, len(sorted)-1))
+
+	return sorted[idx]
+}
@@ -0,0 +1,91 @@
+package tonemap
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestOperator_Apply(t *testing.T) {
+	tcs := []struct {
+		name   string
+		op     Operator
+		values []float64
+		want   []float64
+	}{
+		{name: "linear", op: Linear{Gain: 2}, values: []float64{0, 1, 2, 4}, want: []float64{0, 0.5, 1, 2}},
+		{name: "linear empty", op: Linear{Gain: 2}, values: []float64{0, 0}, want: []float64{0, 0}},
+		{name: "gamma", op: Gamma{Gamma: 0.5}, values: []float64{0, 4, 9, -1}, want: []float64{0, 2, 3, -1}},
+		{name: "log", op: Log{Scale: 1}, values: []float64{0, math.E - 1, math.E*math.E - 1}, want: []float64{0, 0.5, 1}},
+		{name: "log scaled", op: Log{Scale: 2}, values: []float64{0, (math.E - 1) / 2, (math.E*math.E - 1) / 2}, want: []float64{0, 0.5, 1}},
+		{name: "equalize", op: Equalize{}, values: []float64{5, 1, 5, 3}, want: []float64{2.0 / 3.0, 0, 2.0 / 3.0, 1.0 / 3.0}},
+		{name: "clip all", op: Clip{Low: 0, High: 100}, values: []float64{0, 5, 10}, want: []float64{0, 0.5, 1}},
+		{name: "clip", op: Clip{Low: 25, High: 75}, values: []float64{4, 3, 2, 1, 0}, want: []float64{1, 1, 0.5, 0, 0}},
+		{name: "clip flat", op: Clip{Low: 0, High: 100}, values: []float64{2, 2, 2}, want: []float64{0, 0, 0}},
+		{name: "chain", op: Chain{Gamma{Gamma: 2}, Linear{Gain: 1}}, values: []float64{1, 2}, want: []float64{0.25, 1}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.op.Apply(tc.values)
+
+			for i, v := range tc.values {
+				if math.Abs(v-tc.want[i]) > 1e-12 {
+					t.Errorf("got %v, want %v", tc.values, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// reverseHeats is how the poincare command originally equalized counts, before the
+// tone map became an Operator.
+func reverseHeats(counts []int) []float64 {
+	heatMap := make(map[int]int)
+	for _, c := range counts {
+		heatMap[c]++
+	}
+
+	var heats []int
+	for heat := range heatMap {
+		heats = append(heats, heat)
+	}
+	sort.Ints(heats)
+
+	reverse := make(map[int]int)
+	for i, heat := range heats {
+		reverse[heat] = i
+	}
+
+	result := make([]float64, len(counts))
+	for i, c := range counts {
+		result[i] = float64(reverse[c]) / float64(len(reverse))
+	}
+
+	return result
+}
+
+func TestEqualize_ReverseHeats(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	counts := make([]int, 10000)
+	values := make([]float64, len(counts))
+	for i := range counts {
+		// Mostly empty pixels, with a long tail of dense ones.
+		counts[i] = int(r.ExpFloat64() * r.ExpFloat64() * 20)
+		values[i] = float64(counts[i])
+	}
+
+	Equalize{}.Apply(values)
+
+	// Equalize multiplies by the inverse of the number of levels, so may round
+	// differently.
+	want := reverseHeats(counts)
+	for i, v := range values {
+		if math.Abs(v-want[i]) > 1e-15 {
+			t.Fatalf("got %v for count %d, want %v", v, counts[i], want[i])
+		}
+	}
+}
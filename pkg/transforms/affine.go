@@ -0,0 +1,44 @@
+package transforms
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"math"
+	"math/rand"
+)
+
+// Affine maps (x, y) to (A x + B y + C, D x + E y + F).
+type Affine struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// IdentityAffine leaves points where they are.
+var IdentityAffine = Affine{A: 1.0, E: 1.0}
+
+// Rotation returns the Affine rotating by angle radians counter-clockwise around
+// the origin, scaling by scale, and then translating by (dx, dy).
+func Rotation(angle, scale, dx, dy float64) Affine {
+	sin, cos := math.Sincos(angle)
+	return Affine{
+		A: scale * cos, B: -scale * sin, C: dx,
+		D: scale * sin, E: scale * cos, F: dy,
+	}
+}
+
+func (a Affine) Apply(p geometry.XY) geometry.XY {
+	return geometry.XY{
+		X: a.A*p.X + a.B*p.Y + a.C,
+		Y: a.D*p.X + a.E*p.Y + a.F,
+	}
+}
+
+func (a Affine) Next(p geometry.XY, _ *rand.Rand) geometry.XY {
+	return a.Apply(p)
+}
+
+// Determinant is the factor a scales areas by, which is negative if it reflects them.
+func (a Affine) Determinant() float64 {
+	return a.A*a.E - a.B*a.D
+}
+
+var _ Transform = Affine{}
@@ -0,0 +1,31 @@
+package transforms
+
+import "math"
+
+// BurningShip is (|Re z| + i|Im z|)^2 + C.
+//
+// Folding z into the first quadrant makes the map non-holomorphic, so it has no
+// complex derivative.
+type BurningShip struct {
+	C complex128
+}
+
+func (b BurningShip) Next(z complex128) complex128 {
+	z = complex(math.Abs(real(z)), math.Abs(imag(z)))
+	return z*z + b.C
+}
+
+func (BurningShip) Degree() float64 {
+	return 2.0
+}
+
+// CriticalPoints is the origin, which parameter plane renders conventionally start from.
+func (BurningShip) CriticalPoints() []complex128 {
+	return []complex128{0}
+}
+
+// At returns b with C replaced by c, making BurningShip a Family over C.
+func (b BurningShip) At(c complex128) ComplexMap {
+	b.C = c
+	return b
+}
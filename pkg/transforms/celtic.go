@@ -0,0 +1,30 @@
+package transforms
+
+import "math"
+
+// Celtic is |Re(z^2)| + i Im(z^2) + C.
+//
+// Folding the real part makes the map non-holomorphic, so it has no complex derivative.
+type Celtic struct {
+	C complex128
+}
+
+func (c Celtic) Next(z complex128) complex128 {
+	z *= z
+	return complex(math.Abs(real(z)), imag(z)) + c.C
+}
+
+func (Celtic) Degree() float64 {
+	return 2.0
+}
+
+// CriticalPoints is the origin, which parameter plane renders conventionally start from.
+func (Celtic) CriticalPoints() []complex128 {
+	return []complex128{0}
+}
+
+// At returns c with C replaced by param, making Celtic a Family over C.
+func (c Celtic) At(param complex128) ComplexMap {
+	c.C = param
+	return c
+}
@@ -0,0 +1,350 @@
+package transforms
+
+import (
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/rng"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"slices"
+	"sync/atomic"
+)
+
+// A ComplexMap is a map of the complex plane which may be iterated.
+type ComplexMap interface {
+	Next(z complex128) complex128
+
+	// Degree is the growth rate of Next for large z: |Next(z)| grows as |z|^Degree.
+	// Escape-time renderers use it to smooth iteration counts.
+	Degree() float64
+}
+
+// A Stepper is a ComplexMap which applies a different map at each iteration.
+//
+// Next applies one full period of the sequence, while Steps gives the map applied
+// at each iteration of the period.
+type Stepper interface {
+	ComplexMap
+
+	// Steps returns the maps applied at each iteration, repeating after the last.
+	Steps() []ComplexMap
+}
+
+// Steps returns the maps m applies at each iteration, repeating after the last,
+// so that any ComplexMap can be iterated one step at a time:
+//
+//	steps := Steps(m)
+//	for i := 0; i < n; i++ {
+//		z = steps[i%len(steps)].Next(z)
+//	}
+func Steps(m ComplexMap) []ComplexMap {
+	if s, ok := m.(Stepper); ok {
+		return s.Steps()
+	}
+	return []ComplexMap{m}
+}
+
+// Differentiable is implemented by holomorphic maps.
+type Differentiable interface {
+	// Derivative returns the complex derivative of Next at z.
+	Derivative(z complex128) complex128
+}
+
+// Stateful is implemented by maps which depend on earlier points of the orbit as
+// well as the current one. Their Next ignores the history.
+type Stateful interface {
+	ComplexMap
+
+	// Start returns a map which remembers the history of a single orbit.
+	// Each orbit needs its own.
+	Start() ComplexMap
+}
+
+// Start returns the map to iterate a new orbit of m with.
+// Only Stateful maps need a new map for each orbit; others are returned unchanged.
+func Start(m ComplexMap) ComplexMap {
+	if s, ok := m.(Stateful); ok {
+		return s.Start()
+	}
+	return m
+}
+
+// startAll starts each map of maps, copying maps only if one of them is Stateful.
+func startAll(maps []ComplexMap) []ComplexMap {
+	var started []ComplexMap
+	for i, m := range maps {
+		s, ok := m.(Stateful)
+		if !ok {
+			continue
+		}
+
+		if started == nil {
+			started = slices.Clone(maps)
+		}
+		started[i] = s.Start()
+	}
+
+	if started == nil {
+		return maps
+	}
+	return started
+}
+
+var (
+	_ ComplexMap = Julia2{}
+	_ ComplexMap = JuliaN{}
+	_ ComplexMap = Linear{}
+	_ Stateful   = Compose{}
+	_ Stepper    = Cycle{}
+	_ Stateful   = Cycle{}
+	_ Stateful   = Hashed{}
+	_ Stateful   = Random{}
+
+	_ Differentiable = Julia2{}
+	_ Differentiable = JuliaN{}
+	_ Differentiable = Linear{}
+	_ Differentiable = Compose{}
+)
+
+// Compose applies each of its maps in order as a single step.
+type Compose []ComplexMap
+
+func (c Compose) Next(z complex128) complex128 {
+	for _, m := range c {
+		z = m.Next(z)
+	}
+	return z
+}
+
+// Derivative is the product of the derivatives of the composed maps along the
+// way, by the chain rule. Maps which aren't Differentiable contribute NaN.
+func (c Compose) Derivative(z complex128) complex128 {
+	d := complex(1, 0)
+	for _, m := range c {
+		dm, ok := m.(Differentiable)
+		if !ok {
+			return cmplx.NaN()
+		}
+
+		d *= dm.Derivative(z)
+		z = m.Next(z)
+	}
+	return d
+}
+
+func (c Compose) Start() ComplexMap {
+	return Compose(startAll(c))
+}
+
+// Degree is the product of the degrees of the composed maps.
+func (c Compose) Degree() float64 {
+	d := 1.0
+	for _, m := range c {
+		d *= m.Degree()
+	}
+	return d
+}
+
+// Cycle applies its maps in turn, one per iteration, repeating from the first
+// after the last.
+type Cycle []ComplexMap
+
+// Alternate applies a and b on alternating iterations, starting with a.
+func Alternate(a, b ComplexMap) Cycle {
+	return Cycle{a, b}
+}
+
+func (c Cycle) Steps() []ComplexMap {
+	return c
+}
+
+// Next applies the full cycle of maps.
+func (c Cycle) Next(z complex128) complex128 {
+	return Compose(c).Next(z)
+}
+
+func (c Cycle) Start() ComplexMap {
+	return Cycle(startAll(c))
+}
+
+// Degree is the degree of the full cycle of maps.
+func (c Cycle) Degree() float64 {
+	return Compose(c).Degree()
+}
+
+// WeightedMap is a ComplexMap chosen by Hashed or Random in proportion to Weight.
+type WeightedMap struct {
+	ComplexMap
+	Weight float64
+}
+
+// totalWeight returns the sum of the weights of maps, which must be non-negative
+// with a positive, finite total.
+func totalWeight(maps []WeightedMap) (float64, error) {
+	total := 0.0
+	for i, m := range maps {
+		if m.Weight < 0.0 || math.IsNaN(m.Weight) {
+			return 0.0, fmt.Errorf("weight %d must be non-negative, got %v", i, m.Weight)
+		}
+		total += m.Weight
+	}
+	if total <= 0.0 || math.IsInf(total, 0) {
+		return 0.0, fmt.Errorf("weights must have a positive, finite total, got %v", total)
+	}
+
+	return total, nil
+}
+
+// choose returns the map p falls on when maps are laid end to end by weight.
+// P must be in [0, total).
+func choose(maps []WeightedMap, p float64) ComplexMap {
+	for _, m := range maps {
+		if p < m.Weight {
+			return m.ComplexMap
+		}
+		p -= m.Weight
+	}
+
+	// Only reachable through rounding; fall back to the last map.
+	return maps[len(maps)-1].ComplexMap
+}
+
+// startWeighted starts each map of maps in a copy of them.
+func startWeighted(maps []WeightedMap) []WeightedMap {
+	maps = slices.Clone(maps)
+	for i := range maps {
+		maps[i].ComplexMap = Start(maps[i].ComplexMap)
+	}
+	return maps
+}
+
+// weightedDegree is the weighted geometric mean of the degrees of maps, the typical
+// growth rate of an orbit.
+func weightedDegree(maps []WeightedMap, total float64) float64 {
+	logDegree := 0.0
+	for _, m := range maps {
+		logDegree += m.Weight * math.Log(m.Degree())
+	}
+	return math.Exp(logDegree / total)
+}
+
+// Hashed applies one of its maps at each step, chosen in proportion to its weight
+// by a hash of Seed and the point being mapped.
+//
+// The choice is a deterministic function of the point, not a draw from a random
+// number generator: an orbit returning to a point takes the same map from it again.
+// In exchange Hashed is safe for concurrent use and orbits are reproducible.
+// Use NewHashed to make one, or Random for choices independent of the point.
+type Hashed struct {
+	Maps []WeightedMap
+	Seed uint64
+
+	total float64
+}
+
+// NewHashed returns the Hashed choosing among maps with seed.
+func NewHashed(maps []WeightedMap, seed uint64) (Hashed, error) {
+	total, err := totalWeight(maps)
+	if err != nil {
+		return Hashed{}, err
+	}
+
+	return Hashed{Maps: maps, Seed: seed, total: total}, nil
+}
+
+func (r Hashed) Next(z complex128) complex128 {
+	h := r.Seed
+	h = mix(h ^ math.Float64bits(real(z)))
+	h = mix(h ^ math.Float64bits(imag(z)))
+	// The top 53 bits give a uniform float in [0, 1).
+	p := float64(h>>11) / (1 << 53) * r.total
+
+	return choose(r.Maps, p).Next(z)
+}
+
+func (r Hashed) Start() ComplexMap {
+	r.Maps = startWeighted(r.Maps)
+	return r
+}
+
+// Degree is the weighted geometric mean of the degrees of the maps, the typical
+// growth rate of an orbit.
+func (r Hashed) Degree() float64 {
+	return weightedDegree(r.Maps, r.total)
+}
+
+// Random applies one of its maps at each step, chosen at random in proportion to
+// its weight.
+//
+// Each orbit started with Start draws from its own stream of Seed, numbered in the
+// order orbits are started. Copies of a Random share the numbering, so orbits started
+// concurrently get distinct streams, though which orbit gets which then depends on
+// scheduling. Next alone applies the heaviest map. Use NewRandom to make one.
+type Random struct {
+	Maps []WeightedMap
+	Seed int64
+
+	total float64
+
+	// orbits is the number of orbits started.
+	orbits *atomic.Int64
+}
+
+// NewRandom returns the Random choosing among maps with streams of seed.
+func NewRandom(maps []WeightedMap, seed int64) (Random, error) {
+	total, err := totalWeight(maps)
+	if err != nil {
+		return Random{}, err
+	}
+
+	return Random{Maps: maps, Seed: seed, total: total, orbits: &atomic.Int64{}}, nil
+}
+
+func (r Random) Next(z complex128) complex128 {
+	heaviest := r.Maps[0]
+	for _, m := range r.Maps[1:] {
+		if m.Weight > heaviest.Weight {
+			heaviest = m
+		}
+	}
+	return heaviest.Next(z)
+}
+
+func (r Random) Start() ComplexMap {
+	orbit := r.orbits.Add(1) - 1
+
+	return &randomOrbit{
+		maps:  startWeighted(r.Maps),
+		total: r.total,
+		rng:   rand.New(rng.NewSource(r.Seed, int(orbit))),
+	}
+}
+
+// Degree is the weighted geometric mean of the degrees of the maps, the typical
+// growth rate of an orbit.
+func (r Random) Degree() float64 {
+	return weightedDegree(r.Maps, r.total)
+}
+
+// randomOrbit is a Random drawing the maps of a single orbit from its own stream.
+type randomOrbit struct {
+	maps  []WeightedMap
+	total float64
+	rng   *rand.Rand
+}
+
+func (o *randomOrbit) Next(z complex128) complex128 {
+	return choose(o.maps, o.rng.Float64()*o.total).Next(z)
+}
+
+func (o *randomOrbit) Degree() float64 {
+	return weightedDegree(o.maps, o.total)
+}
+
+// mix is the SplitMix64 finalizer.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,110 @@
+package transforms
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewWeighted(t *testing.T) {
+	tcs := []struct {
+		name    string
+		maps    []WeightedMap
+		wantErr bool
+	}{
+		{name: "empty", wantErr: true},
+		{name: "zero weights", maps: []WeightedMap{{ComplexMap: Julia2{}, Weight: 0}}, wantErr: true},
+		{name: "negative weight", maps: []WeightedMap{{ComplexMap: Julia2{}, Weight: 2}, {ComplexMap: Julia2{C: 1}, Weight: -1}}, wantErr: true},
+		{name: "one map", maps: []WeightedMap{{ComplexMap: Julia2{C: -1}, Weight: 1}}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewHashed(tc.maps, 1)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("NewHashed: got error %v, want error %t", err, tc.wantErr)
+			}
+
+			_, err = NewRandom(tc.maps, 1)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("NewRandom: got error %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashed_Next(t *testing.T) {
+	h, err := NewHashed([]WeightedMap{
+		{ComplexMap: Linear{Multiply: 1, Add: 1}, Weight: 1},
+		{ComplexMap: Linear{Multiply: 1, Add: -1}, Weight: 3},
+	}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up := 0
+	for i := 0; i < 10000; i++ {
+		z := complex(float64(i), 0.5)
+		got := h.Next(z)
+
+		// The choice is a function of the point.
+		if again := h.Next(z); again != got {
+			t.Fatalf("Next(%v) gave %v and then %v", z, got, again)
+		}
+		if got == z+1 {
+			up++
+		}
+	}
+
+	// Maps are chosen in proportion to their weights.
+	if up < 2000 || up > 3000 {
+		t.Errorf("chose the map of weight 1 in 4 %d times of 10000", up)
+	}
+}
+
+func TestRandom_Start(t *testing.T) {
+	maps := []WeightedMap{
+		{ComplexMap: Linear{Multiply: 1, Add: 1}, Weight: 1},
+		{ComplexMap: Linear{Multiply: 1, Add: -1}, Weight: 3},
+	}
+
+	// orbit returns the points the next orbit of r maps 0.5 to at each step.
+	orbit := func(r Random) []complex128 {
+		m := r.Start()
+		steps := make([]complex128, 10000)
+		for i := range steps {
+			// The same point each time, so only the stream decides.
+			steps[i] = m.Next(0.5)
+		}
+		return steps
+	}
+
+	r, err := NewRandom(maps, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := orbit(r), orbit(r)
+
+	if slices.Equal(first, second) {
+		t.Error("two orbits chose the same maps")
+	}
+
+	// Orbits draw from streams numbered in the order they start.
+	again, err := NewRandom(maps, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(orbit(again), first) {
+		t.Error("the first orbit of a Random with the same seed chose different maps")
+	}
+
+	// Maps are chosen in proportion to their weights.
+	up := 0
+	for _, step := range first {
+		if step == 1.5 {
+			up++
+		}
+	}
+	if up < 2000 || up > 3000 {
+		t.Errorf("chose the map of weight 1 in 4 %d times of 10000", up)
+	}
+}
@@ -0,0 +1,111 @@
+package transforms
+
+// A Family is a set of ComplexMaps indexed by a complex parameter, such as the
+// maps z^2 + c indexed by c whose parameter plane is the Mandelbrot set.
+type Family interface {
+	At(c complex128) ComplexMap
+}
+
+// Critical is implemented by maps which know where their derivative vanishes.
+type Critical interface {
+	// CriticalPoints returns the points where the derivative of Next is zero.
+	// Points whose orbits tell maps of a family apart are enough: those joining
+	// another's orbit after a step, or fixed for every map of the family, may be
+	// left out. Maps which aren't holomorphic return the points their parameter
+	// planes are conventionally iterated from instead.
+	CriticalPoints() []complex128
+}
+
+// A CriticalPoint is a critical point of one of the maps a Stepper applies.
+type CriticalPoint struct {
+	// Step is the index into Steps of the map Z is a critical point of.
+	// Orbits from Z start by applying that map.
+	Step int
+
+	Z complex128
+}
+
+// CriticalPoints returns the critical points of each of the maps m applies.
+// Maps which don't implement Critical are assumed to have none.
+func CriticalPoints(m ComplexMap) []CriticalPoint {
+	var result []CriticalPoint
+
+	for i, step := range Steps(m) {
+		c, ok := step.(Critical)
+		if !ok {
+			continue
+		}
+
+		for _, z := range c.CriticalPoints() {
+			result = append(result, CriticalPoint{Step: i, Z: z})
+		}
+	}
+
+	return result
+}
+
+var (
+	_ Family = Mandelbrot{}
+	_ Family = Multibrot{}
+	_ Family = AlternateFamily{}
+
+	_ Family = JuliaN{}
+	_ Family = BurningShip{}
+	_ Family = Tricorn{}
+	_ Family = Celtic{}
+	_ Family = Magnet1{}
+	_ Family = Magnet2{}
+	_ Family = Phoenix{}
+	_ Family = McMullen{}
+
+	_ Critical = Julia2{}
+	_ Critical = JuliaN{}
+	_ Critical = BurningShip{}
+	_ Critical = Tricorn{}
+	_ Critical = Celtic{}
+	_ Critical = Magnet1{}
+	_ Critical = Magnet2{}
+	_ Critical = Phoenix{}
+	_ Critical = McMullen{}
+
+	_ Differentiable = Magnet1{}
+	_ Differentiable = Magnet2{}
+	_ Differentiable = McMullen{}
+
+	_ Stateful = Phoenix{}
+)
+
+func (Julia2) CriticalPoints() []complex128 {
+	return []complex128{0}
+}
+
+// CriticalPoints is the origin if the real part of N exceeds one.
+// Otherwise the origin is a pole or branch point rather than a critical point.
+func (j JuliaN) CriticalPoints() []complex128 {
+	if real(j.N) <= 1.0 {
+		return nil
+	}
+	return []complex128{0}
+}
+
+// Multibrot is the family z^N + c.
+type Multibrot struct {
+	N complex128
+}
+
+func (m Multibrot) At(c complex128) ComplexMap {
+	if m.N == 2 {
+		return Julia2{C: c}
+	}
+	return JuliaN{N: m.N, C: c}
+}
+
+// AlternateFamily alternates z^N + c with a fixed map.
+type AlternateFamily struct {
+	N     complex128
+	Other ComplexMap
+}
+
+func (a AlternateFamily) At(c complex128) ComplexMap {
+	return Alternate(JuliaN{N: a.N, C: c}, a.Other)
+}
@@ -10,6 +10,14 @@ func (j Julia2) Next(z complex128) complex128 {
 	return z*z + j.C
 }
 
+func (j Julia2) Derivative(z complex128) complex128 {
+	return 2 * z
+}
+
+func (Julia2) Degree() float64 {
+	return 2.0
+}
+
 type JuliaN struct {
 	N complex128
 	C complex128
@@ -18,3 +26,18 @@ type JuliaN struct {
 func (j JuliaN) Next(z complex128) complex128 {
 	return cmplx.Pow(z, j.N) + j.C
 }
+
+func (j JuliaN) Derivative(z complex128) complex128 {
+	return j.N * cmplx.Pow(z, j.N-1)
+}
+
+// At returns j with C replaced by c, making JuliaN a Family over C.
+func (j JuliaN) At(c complex128) ComplexMap {
+	j.C = c
+	return j
+}
+
+// Degree is the real part of N, since the imaginary part only rotates z.
+func (j JuliaN) Degree() float64 {
+	return real(j.N)
+}
@@ -8,3 +8,11 @@ type Linear struct {
 func (l Linear) Next(z complex128) complex128 {
 	return z*l.Multiply + l.Add
 }
+
+func (Linear) Degree() float64 {
+	return 1.0
+}
+
+func (l Linear) Derivative(complex128) complex128 {
+	return l.Multiply
+}
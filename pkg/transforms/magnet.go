@@ -0,0 +1,105 @@
+package transforms
+
+import "math/cmplx"
+
+// Magnet1 is the type I magnet map ((z^2 + C - 1) / (2z + C - 2))^2, from the
+// renormalization of a model of magnetism.
+//
+// Besides escaping, orbits may converge to the fixed point 1.
+type Magnet1 struct {
+	C complex128
+}
+
+func (m Magnet1) Next(z complex128) complex128 {
+	q := (z*z + m.C - 1) / (2*z + m.C - 2)
+	return q * q
+}
+
+func (m Magnet1) Derivative(z complex128) complex128 {
+	u, du := z*z+m.C-1, 2*z
+	v, dv := 2*z+m.C-2, complex(2, 0)
+	return 2 * u * (du*v - u*dv) / (v * v * v)
+}
+
+func (Magnet1) Degree() float64 {
+	return 2.0
+}
+
+// CriticalPoints are sqrt(1-C), a root of the numerator, and 1-C, where the
+// derivative of the quotient vanishes. The other root -sqrt(1-C) joins the orbit of
+// sqrt(1-C) at the origin after a step, which is why renders conventionally start
+// there, and the fixed point 1 is superattracting for every C.
+func (m Magnet1) CriticalPoints() []complex128 {
+	return []complex128{cmplx.Sqrt(1 - m.C), 1 - m.C}
+}
+
+// At returns m with C replaced by c, making Magnet1 a Family over C.
+func (m Magnet1) At(c complex128) ComplexMap {
+	m.C = c
+	return m
+}
+
+// Magnet2 is the type II magnet map
+//
+//	((z^3 + 3(C-1)z + (C-1)(C-2)) / (3z^2 + 3(C-2)z + (C-1)(C-2) + 1))^2
+//
+// Besides escaping, orbits may converge to the fixed point 1.
+type Magnet2 struct {
+	C complex128
+}
+
+func (m Magnet2) parts(z complex128) (u, du, v, dv complex128) {
+	c1, c2 := m.C-1, m.C-2
+	u = z*z*z + 3*c1*z + c1*c2
+	du = 3*z*z + 3*c1
+	v = 3*z*z + 3*c2*z + c1*c2 + 1
+	dv = 6*z + 3*c2
+	return u, du, v, dv
+}
+
+func (m Magnet2) Next(z complex128) complex128 {
+	u, _, v, _ := m.parts(z)
+	q := u / v
+	return q * q
+}
+
+func (m Magnet2) Derivative(z complex128) complex128 {
+	u, du, v, dv := m.parts(z)
+	return 2 * u * (du*v - u*dv) / (v * v * v)
+}
+
+func (Magnet2) Degree() float64 {
+	return 2.0
+}
+
+// CriticalPoints are a root of the numerator and 1-C, where the derivative of the
+// quotient vanishes. As for Magnet1, the other roots of the numerator join the orbit
+// of the first at the origin after a step, and the fixed point 1 is superattracting
+// for every C.
+func (m Magnet2) CriticalPoints() []complex128 {
+	// The numerator is the depressed cubic z^3 + 3az + b, which Cardano's formula
+	// solves as s - a/s where s^3 = -b/2 ± sqrt(b^2/4 + a^3). Taking the larger
+	// choice of s avoids cancellation.
+	a := m.C - 1
+	b := a * (m.C - 2)
+
+	d := cmplx.Sqrt(b*b/4 + a*a*a)
+	s3 := -b/2 + d
+	if cmplx.Abs(-b/2-d) > cmplx.Abs(s3) {
+		s3 = -b/2 - d
+	}
+
+	root := complex(0, 0)
+	if s3 != 0 {
+		s := cmplx.Pow(s3, 1.0/3.0)
+		root = s - a/s
+	}
+
+	return []complex128{root, 1 - m.C}
+}
+
+// At returns m with C replaced by c, making Magnet2 a Family over C.
+func (m Magnet2) At(c complex128) ComplexMap {
+	m.C = c
+	return m
+}
@@ -0,0 +1,45 @@
+package transforms
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestMagnet_CriticalPoints(t *testing.T) {
+	tcs := []struct {
+		name string
+		m    interface {
+			Differentiable
+			Critical
+			Next(z complex128) complex128
+		}
+	}{
+		{name: "magnet1", m: Magnet1{C: 1.5 + 0.5i}},
+		{name: "magnet1 real", m: Magnet1{C: 3}},
+		{name: "magnet1 one", m: Magnet1{C: 1}},
+		{name: "magnet2", m: Magnet2{C: 1.5 + 0.5i}},
+		{name: "magnet2 real", m: Magnet2{C: 3}},
+		{name: "magnet2 negative", m: Magnet2{C: -0.7 - 2i}},
+		{name: "magnet2 one", m: Magnet2{C: 1}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			points := tc.m.CriticalPoints()
+			if len(points) != 2 {
+				t.Fatalf("got %d critical points, want 2", len(points))
+			}
+
+			for _, z := range points {
+				if d := tc.m.Derivative(z); cmplx.Abs(d) > 1e-9 {
+					t.Errorf("got derivative %v at critical point %v, want 0", d, z)
+				}
+			}
+
+			// The first is a root of the numerator, so maps to the origin.
+			if z := tc.m.Next(points[0]); cmplx.Abs(z) > 1e-9 {
+				t.Errorf("got %v after critical point %v, want 0", z, points[0])
+			}
+		})
+	}
+}
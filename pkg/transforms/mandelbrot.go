@@ -7,3 +7,8 @@ type Mandelbrot struct {
 func (m Mandelbrot) Next(z complex128, c complex128) complex128 {
 	return z*z + c + m.C
 }
+
+// At returns the map m iterates for the parameter c.
+func (m Mandelbrot) At(c complex128) ComplexMap {
+	return Julia2{C: c + m.C}
+}
@@ -0,0 +1,65 @@
+package transforms
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// McMullen is z^N + C/z^M, a rational map whose parameter plane holds copies of
+// Sierpinski curves and Cantor sets of circles. N and M must be at least 1.
+type McMullen struct {
+	N, M int
+	C    complex128
+}
+
+func (m McMullen) Next(z complex128) complex128 {
+	return pow(z, m.N) + m.C/pow(z, m.M)
+}
+
+func (m McMullen) Derivative(z complex128) complex128 {
+	n, mm := complex(float64(m.N), 0), complex(float64(m.M), 0)
+	return n*pow(z, m.N-1) - mm*m.C/pow(z, m.M+1)
+}
+
+func (m McMullen) Degree() float64 {
+	return float64(m.N)
+}
+
+// CriticalPoints are the N+M roots of z^(N+M) = M*C/N, besides the pole at the origin.
+func (m McMullen) CriticalPoints() []complex128 {
+	k := m.N + m.M
+	w := complex(float64(m.M), 0) * m.C / complex(float64(m.N), 0)
+
+	r := math.Pow(cmplx.Abs(w), 1.0/float64(k))
+	theta := cmplx.Phase(w) / float64(k)
+
+	points := make([]complex128, k)
+	for i := range points {
+		points[i] = cmplx.Rect(r, theta+2*math.Pi*float64(i)/float64(k))
+	}
+
+	return points
+}
+
+// At returns m with C replaced by c, making McMullen a Family over C.
+func (m McMullen) At(c complex128) ComplexMap {
+	m.C = c
+	return m
+}
+
+// pow raises z to an integer power by repeated squaring, which is faster and more
+// accurate than cmplx.Pow.
+func pow(z complex128, n int) complex128 {
+	if n < 0 {
+		return 1 / pow(z, -n)
+	}
+
+	result := complex(1, 0)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result *= z
+		}
+		z *= z
+	}
+	return result
+}
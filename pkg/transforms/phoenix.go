@@ -0,0 +1,44 @@
+package transforms
+
+// Phoenix is z^2 + C + P*y, where y is the point before z in the orbit.
+//
+// Next alone treats y as zero; use Start to iterate orbits.
+type Phoenix struct {
+	C complex128
+	P complex128
+}
+
+func (p Phoenix) Next(z complex128) complex128 {
+	return z*z + p.C
+}
+
+func (Phoenix) Degree() float64 {
+	return 2.0
+}
+
+// CriticalPoints is the origin, which parameter plane renders conventionally start from.
+func (Phoenix) CriticalPoints() []complex128 {
+	return []complex128{0}
+}
+
+func (p Phoenix) Start() ComplexMap {
+	return &phoenixOrbit{Phoenix: p}
+}
+
+// At returns p with C replaced by c, making Phoenix a Family over C.
+func (p Phoenix) At(c complex128) ComplexMap {
+	p.C = c
+	return p
+}
+
+// phoenixOrbit is a Phoenix which remembers the previous point of its orbit.
+type phoenixOrbit struct {
+	Phoenix
+	prev complex128
+}
+
+func (o *phoenixOrbit) Next(z complex128) complex128 {
+	next := z*z + o.C + o.P*o.prev
+	o.prev = z
+	return next
+}
@@ -1,7 +1,9 @@
 package transforms
 
 import (
+	"fmt"
 	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"math"
 	"math/rand"
 )
 
@@ -15,26 +17,84 @@ type Transform interface {
 	Next(geometry.XY, *rand.Rand) geometry.XY
 }
 
+// A StartPoint is an InitialTransform which always starts at the same point.
+type StartPoint geometry.XY
+
+func (s StartPoint) First() geometry.XY {
+	return geometry.XY(s)
+}
+
+// TransformProbability is one of the Transforms of a ProbabilisticTransform.
 type TransformProbability struct {
 	Transform
+
+	// Probability is the cumulative probability of choosing this or an earlier
+	// Transform, so it increases through the list.
 	Probability float64
 }
 
+// ProbabilisticTransform applies one of Transforms chosen at random, restarting from
+// First if none is chosen. Iterating it is the chaos game: its points approach the
+// attractor of the iterated function system of Transforms.
 type ProbabilisticTransform struct {
 	InitialTransform
 	Transforms []TransformProbability
 }
 
+// NewProbabilisticTransform returns the ProbabilisticTransform choosing transforms[i]
+// with probability proportional to weights[i], which must be non-negative and not all zero.
+func NewProbabilisticTransform(initial InitialTransform, transforms []Transform, weights []float64) (ProbabilisticTransform, error) {
+	if len(transforms) != len(weights) {
+		return ProbabilisticTransform{}, fmt.Errorf("got %d transforms but %d weights", len(transforms), len(weights))
+	}
+
+	total := 0.0
+	for i, w := range weights {
+		if w < 0.0 || math.IsNaN(w) {
+			return ProbabilisticTransform{}, fmt.Errorf("weight %d must be non-negative, got %v", i, w)
+		}
+		total += w
+	}
+	if total <= 0.0 || math.IsInf(total, 0) {
+		return ProbabilisticTransform{}, fmt.Errorf("weights must have a positive, finite total, got %v", total)
+	}
+
+	result := ProbabilisticTransform{InitialTransform: initial}
+	cumulative := 0.0
+	for i, t := range transforms {
+		cumulative += weights[i]
+		result.Transforms = append(result.Transforms, TransformProbability{Transform: t, Probability: cumulative / total})
+	}
+	// Make sure rounding never leaves a gap before one for Next to fall through.
+	result.Transforms[len(result.Transforms)-1].Probability = 1.0
+
+	return result, nil
+}
+
 func (pt ProbabilisticTransform) Next(xy geometry.XY, rng *rand.Rand) geometry.XY {
+	i := pt.Choose(rng)
+	if i < 0 {
+		return pt.First()
+	}
+
+	return pt.Transforms[i].Next(xy, rng)
+}
+
+// Choose returns the index of the Transform Next applies, chosen at random,
+// or -1 if none is chosen and Next restarts from First.
+func (pt ProbabilisticTransform) Choose(rng *rand.Rand) int {
 	p := rng.Float64()
 
-	for _, maxProb := range pt.Transforms {
+	for i, maxProb := range pt.Transforms {
 		if p < maxProb.Probability {
-			return maxProb.Next(xy, rng)
+			return i
 		}
 	}
 
-	return pt.First()
+	return -1
 }
 
-var _ Transform = ProbabilisticTransform{}
+var (
+	_ Transform        = ProbabilisticTransform{}
+	_ InitialTransform = StartPoint{}
+)
@@ -0,0 +1,30 @@
+package transforms
+
+import "math/cmplx"
+
+// Tricorn is conj(z)^2 + C. Its parameter plane is also called the Mandelbar set.
+//
+// Conjugation makes the map non-holomorphic, so it has no complex derivative.
+type Tricorn struct {
+	C complex128
+}
+
+func (t Tricorn) Next(z complex128) complex128 {
+	z = cmplx.Conj(z)
+	return z*z + t.C
+}
+
+func (Tricorn) Degree() float64 {
+	return 2.0
+}
+
+// CriticalPoints is the origin, which parameter plane renders conventionally start from.
+func (Tricorn) CriticalPoints() []complex128 {
+	return []complex128{0}
+}
+
+// At returns t with C replaced by c, making Tricorn a Family over C.
+func (t Tricorn) At(c complex128) ComplexMap {
+	t.C = c
+	return t
+}
@@ -0,0 +1,281 @@
+package transforms
+
+import (
+	"fmt"
+	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Variation is a nonlinear map of the plane, as in the fractal flame algorithm
+// of Draves and Reckase. Variations which pick one of several images of a point,
+// such as julia, use r to choose.
+type Variation func(p geometry.XY, r *rand.Rand) geometry.XY
+
+// Variations are the variations by name. Angles are measured as in flam3, so that
+// variations draw as they do there.
+var Variations = map[string]Variation{
+	"linear":       linear,
+	"sinusoidal":   sinusoidal,
+	"spherical":    spherical,
+	"swirl":        swirl,
+	"horseshoe":    horseshoe,
+	"polar":        polar,
+	"handkerchief": handkerchief,
+	"heart":        heart,
+	"disc":         disc,
+	"spiral":       spiral,
+	"hyperbolic":   hyperbolic,
+	"diamond":      diamond,
+	"ex":           ex,
+	"julia":        julia,
+	"bent":         bent,
+	"fisheye":      fisheye,
+	"exponential":  exponential,
+	"power":        power,
+	"cosine":       cosine,
+	"eyefish":      eyefish,
+	"bubble":       bubble,
+	"cylinder":     cylinder,
+	"tangent":      tangent,
+	"cross":        cross,
+	"blur":         blur,
+}
+
+// VariationNames returns the names of Variations in alphabetical order.
+func VariationNames() []string {
+	names := make([]string, 0, len(Variations))
+	for name := range Variations {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// variationEpsilon keeps variations which divide by the distance from the origin
+// finite there.
+const variationEpsilon = 1e-10
+
+// radius returns the distance of p from the origin.
+func radius(p geometry.XY) float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// theta returns the angle of p measured from the positive y axis toward the
+// positive x axis, which is the angle flam3's variations are defined with.
+func theta(p geometry.XY) float64 {
+	return math.Atan2(p.X, p.Y)
+}
+
+func linear(p geometry.XY, _ *rand.Rand) geometry.XY {
+	return p
+}
+
+func sinusoidal(p geometry.XY, _ *rand.Rand) geometry.XY {
+	return geometry.XY{X: math.Sin(p.X), Y: math.Sin(p.Y)}
+}
+
+func spherical(p geometry.XY, _ *rand.Rand) geometry.XY {
+	s := 1.0 / (p.X*p.X + p.Y*p.Y + variationEpsilon)
+	return geometry.XY{X: s * p.X, Y: s * p.Y}
+}
+
+func swirl(p geometry.XY, _ *rand.Rand) geometry.XY {
+	sin, cos := math.Sincos(p.X*p.X + p.Y*p.Y)
+	return geometry.XY{X: p.X*sin - p.Y*cos, Y: p.X*cos + p.Y*sin}
+}
+
+func horseshoe(p geometry.XY, _ *rand.Rand) geometry.XY {
+	s := 1.0 / (radius(p) + variationEpsilon)
+	return geometry.XY{X: s * (p.X - p.Y) * (p.X + p.Y), Y: s * 2.0 * p.X * p.Y}
+}
+
+func polar(p geometry.XY, _ *rand.Rand) geometry.XY {
+	return geometry.XY{X: theta(p) / math.Pi, Y: radius(p) - 1.0}
+}
+
+func handkerchief(p geometry.XY, _ *rand.Rand) geometry.XY {
+	r, t := radius(p), theta(p)
+	return geometry.XY{X: r * math.Sin(t+r), Y: r * math.Cos(t-r)}
+}
+
+func heart(p geometry.XY, _ *rand.Rand) geometry.XY {
+	r, t := radius(p), theta(p)
+	sin, cos := math.Sincos(t * r)
+	return geometry.XY{X: r * sin, Y: -r * cos}
+}
+
+func disc(p geometry.XY, _ *rand.Rand) geometry.XY {
+	s := theta(p) / math.Pi
+	sin, cos := math.Sincos(math.Pi * radius(p))
+	return geometry.XY{X: s * sin, Y: s * cos}
+}
+
+func spiral(p geometry.XY, _ *rand.Rand) geometry.XY {
+	r, t := radius(p)+variationEpsilon, theta(p)
+	return geometry.XY{X: (math.Cos(t) + math.Sin(r)) / r, Y: (math.Sin(t) - math.Cos(r)) / r}
+}
+
+func hyperbolic(p geometry.XY, _ *rand.Rand) geometry.XY {
+	r, t := radius(p)+variationEpsilon, theta(p)
+	return geometry.XY{X: math.Sin(t) / r, Y: r * math.Cos(t)}
+}
+
+func diamond(p geometry.XY, _ *rand.Rand) geometry.XY {
+	r, t := radius(p), theta(p)
+	return geometry.XY{X: math.Sin(t) * math.Cos(r), Y: math.Cos(t) * math.Sin(r)}
+}
+
+func ex(p geometry.XY, _ *rand.Rand) geometry.XY {
+	r, t := radius(p), theta(p)
+	p0 := math.Sin(t + r)
+	p1 := math.Cos(t - r)
+	p0, p1 = p0*p0*p0, p1*p1*p1
+	return geometry.XY{X: r * (p0 + p1), Y: r * (p0 - p1)}
+}
+
+// julia takes one of the two square roots of p, chosen at random. Unlike the
+// variations using theta, it measures angles from the positive x axis, as flam3 does.
+func julia(p geometry.XY, r *rand.Rand) geometry.XY {
+	t := 0.5 * math.Atan2(p.Y, p.X)
+	if r.Intn(2) == 1 {
+		t += math.Pi
+	}
+
+	s := math.Sqrt(radius(p))
+	sin, cos := math.Sincos(t)
+	return geometry.XY{X: s * cos, Y: s * sin}
+}
+
+func bent(p geometry.XY, _ *rand.Rand) geometry.XY {
+	x, y := p.X, p.Y
+	if x < 0.0 {
+		x *= 2.0
+	}
+	if y < 0.0 {
+		y *= 0.5
+	}
+	return geometry.XY{X: x, Y: y}
+}
+
+func fisheye(p geometry.XY, _ *rand.Rand) geometry.XY {
+	// Swaps the coordinates, unlike eyefish.
+	s := 2.0 / (radius(p) + 1.0)
+	return geometry.XY{X: s * p.Y, Y: s * p.X}
+}
+
+func exponential(p geometry.XY, _ *rand.Rand) geometry.XY {
+	s := math.Exp(p.X - 1.0)
+	sin, cos := math.Sincos(math.Pi * p.Y)
+	return geometry.XY{X: s * cos, Y: s * sin}
+}
+
+func power(p geometry.XY, _ *rand.Rand) geometry.XY {
+	sin, cos := math.Sincos(theta(p))
+	s := math.Pow(radius(p), sin)
+	return geometry.XY{X: s * cos, Y: s * sin}
+}
+
+func cosine(p geometry.XY, _ *rand.Rand) geometry.XY {
+	sin, cos := math.Sincos(math.Pi * p.X)
+	return geometry.XY{X: cos * math.Cosh(p.Y), Y: -sin * math.Sinh(p.Y)}
+}
+
+func eyefish(p geometry.XY, _ *rand.Rand) geometry.XY {
+	s := 2.0 / (radius(p) + 1.0)
+	return geometry.XY{X: s * p.X, Y: s * p.Y}
+}
+
+func bubble(p geometry.XY, _ *rand.Rand) geometry.XY {
+	s := 4.0 / (p.X*p.X + p.Y*p.Y + 4.0)
+	return geometry.XY{X: s * p.X, Y: s * p.Y}
+}
+
+func cylinder(p geometry.XY, _ *rand.Rand) geometry.XY {
+	return geometry.XY{X: math.Sin(p.X), Y: p.Y}
+}
+
+func tangent(p geometry.XY, _ *rand.Rand) geometry.XY {
+	return geometry.XY{X: math.Sin(p.X) / math.Cos(p.Y), Y: math.Tan(p.Y)}
+}
+
+func cross(p geometry.XY, _ *rand.Rand) geometry.XY {
+	d := p.X*p.X - p.Y*p.Y
+	s := 1.0 / (math.Abs(d) + variationEpsilon)
+	return geometry.XY{X: s * p.X, Y: s * p.Y}
+}
+
+// blur ignores p, giving a random point of the unit disc, denser toward the center.
+func blur(_ geometry.XY, r *rand.Rand) geometry.XY {
+	s := r.Float64()
+	sin, cos := math.Sincos(2.0 * math.Pi * r.Float64())
+	return geometry.XY{X: s * cos, Y: s * sin}
+}
+
+// A WeightedVariation is a Variation with the weight it is blended with.
+type WeightedVariation struct {
+	Name      string
+	Variation Variation
+	Weight    float64
+}
+
+// ParseVariations parses comma-separated NAME:WEIGHT pairs, such as
+// "swirl:0.5,julia:0.5". A NAME alone has weight one.
+func ParseVariations(spec string) ([]WeightedVariation, error) {
+	var result []WeightedVariation
+	for _, field := range strings.Split(spec, ",") {
+		name, weight, hasWeight := strings.Cut(strings.TrimSpace(field), ":")
+
+		v, ok := Variations[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown variation %q, want one of %s", name, strings.Join(VariationNames(), ", "))
+		}
+
+		w := 1.0
+		if hasWeight {
+			var err error
+			w, err = strconv.ParseFloat(weight, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing weight %q of variation %s: %w", weight, name, err)
+			}
+		}
+
+		result = append(result, WeightedVariation{Name: name, Variation: v, Weight: w})
+	}
+
+	return result, nil
+}
+
+// A Blend is a transform of a fractal flame: Affine, followed by the sum of
+// Variations scaled by their weights, followed by Post if set.
+// With no Variations, the sum is skipped rather than taken to be zero.
+type Blend struct {
+	Affine     Affine
+	Variations []WeightedVariation
+	Post       *Affine
+}
+
+func (b Blend) Next(p geometry.XY, r *rand.Rand) geometry.XY {
+	p = b.Affine.Apply(p)
+
+	if len(b.Variations) > 0 {
+		var sum geometry.XY
+		for _, v := range b.Variations {
+			q := v.Variation(p, r)
+			sum.X += v.Weight * q.X
+			sum.Y += v.Weight * q.Y
+		}
+		p = sum
+	}
+
+	if b.Post != nil {
+		p = b.Post.Apply(p)
+	}
+
+	return p
+}
+
+var _ Transform = Blend{}
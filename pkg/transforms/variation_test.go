@@ -0,0 +1,68 @@
+package transforms
+
+import (
+	"github.com/willbeason/tree-fractal/pkg/geometry"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestJulia(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, p := range []geometry.XY{{X: 1, Y: 0}, {X: 0, Y: 1}, {X: -2, Y: 0.5}, {X: 0.3, Y: -4}} {
+		for i := 0; i < 10; i++ {
+			q := julia(p, r)
+
+			// q is a square root of p.
+			x, y := q.X*q.X-q.Y*q.Y, 2*q.X*q.Y
+			if math.Abs(x-p.X) > 1e-12 || math.Abs(y-p.Y) > 1e-12 {
+				t.Errorf("julia(%v) = %v, whose square is (%v, %v)", p, q, x, y)
+			}
+		}
+	}
+}
+
+func TestParseVariations(t *testing.T) {
+	tcs := []struct {
+		name    string
+		spec    string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{name: "name alone", spec: "julia", want: map[string]float64{"julia": 1}},
+		{name: "weights", spec: "swirl:0.5, linear:0.25", want: map[string]float64{"swirl": 0.5, "linear": 0.25}},
+		{name: "negative weight", spec: "spherical:-1", want: map[string]float64{"spherical": -1}},
+		{name: "unknown", spec: "swirly", wantErr: true},
+		{name: "bad weight", spec: "swirl:half", wantErr: true},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "trailing comma", spec: "swirl,", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseVariations(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d variations, want %d", len(got), len(tc.want))
+			}
+			for _, v := range got {
+				if w, ok := tc.want[v.Name]; !ok || w != v.Weight {
+					t.Errorf("got %s with weight %v, want %v", v.Name, v.Weight, tc.want)
+				}
+				if v.Variation == nil {
+					t.Errorf("got no Variation for %s", v.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package viewport
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Viewport frames a rectangular region of the plane onto a grid of pixels.
+//
+// Pixel (0, 0) is the top-left corner of the image, and plane Y increases upward
+// unless FlipY is set.
+type Viewport struct {
+	// Width and Height are the dimensions of the image in pixels.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// CenterX and CenterY are the plane coordinates of the center of the image.
+	CenterX float64 `json:"centerX"`
+	CenterY float64 `json:"centerY"`
+
+	// Center, if set, is the center of the image as "x,y" in decimal to any precision.
+	// It overrides CenterX and CenterY, which ResolveCenter rounds it into. Only deep
+	// renders by perturbation, as with param --deep, use its full precision to zoom
+	// deeper than float64s can locate; other renders see the rounded center.
+	Center string `json:"center,omitempty"`
+
+	// ViewHeight is the vertical extent of the view in plane units.
+	ViewHeight float64 `json:"viewHeight"`
+
+	// Rotation is the counter-clockwise rotation of the view in radians.
+	Rotation float64 `json:"rotation,omitempty"`
+
+	// Aspect is the ratio of the horizontal to the vertical plane size of a pixel.
+	// Zero is treated as 1.0, or square pixels.
+	Aspect float64 `json:"aspect,omitempty"`
+
+	// FlipY mirrors the plane about the center so plane Y increases downward.
+	// Rotation still turns the view counter-clockwise on the image.
+	FlipY bool `json:"flipY,omitempty"`
+}
+
+// AddFlags registers flags which override the framing of v.
+// The current values of v are the defaults. Resolution is left to the caller.
+func (v *Viewport) AddFlags(flags *pflag.FlagSet) {
+	flags.Float64Var(&v.CenterX, "center-x", v.CenterX, "horizontal plane coordinate of the center of the image")
+	flags.Float64Var(&v.CenterY, "center-y", v.CenterY, "vertical plane coordinate of the center of the image")
+	flags.StringVar(&v.Center, "center", v.Center, "center of the image as x,y in decimal, overriding --center-x and --center-y; kept to full precision only by deep renders such as param --deep")
+	flags.Float64Var(&v.ViewHeight, "view-height", v.ViewHeight, "vertical extent of the view in plane units")
+	flags.Float64Var(&v.Rotation, "rotation", v.Rotation, "counter-clockwise rotation of the view in radians")
+	flags.Float64Var(&v.Aspect, "aspect", v.Aspect, "ratio of horizontal to vertical pixel size; 0 for square pixels")
+	flags.BoolVar(&v.FlipY, "flip-y", v.FlipY, "make the vertical plane coordinate increase down the image")
+}
+
+// ResolveCenter sets CenterX and CenterY to the nearest float64s to Center, if set.
+func (v *Viewport) ResolveCenter() error {
+	if v.Center == "" {
+		return nil
+	}
+
+	x, y, ok := strings.Cut(v.Center, ",")
+	if !ok {
+		return fmt.Errorf("parsing center %q: want x,y", v.Center)
+	}
+
+	centerX, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+	if err != nil {
+		return fmt.Errorf("parsing center %q: %w", v.Center, err)
+	}
+
+	centerY, err := strconv.ParseFloat(strings.TrimSpace(y), 64)
+	if err != nil {
+		return fmt.Errorf("parsing center %q: %w", v.Center, err)
+	}
+
+	v.CenterX, v.CenterY = centerX, centerY
+	return nil
+}
+
+// ViewWidth is the horizontal extent of the view in plane units.
+func (v Viewport) ViewWidth() float64 {
+	return v.ViewHeight * v.aspect() * float64(v.Width) / float64(v.Height)
+}
+
+func (v Viewport) aspect() float64 {
+	if v.Aspect == 0.0 {
+		return 1.0
+	}
+	return v.Aspect
+}
+
+// Frame precomputes the mapping between v's plane coordinates and pixels.
+// Returns an error if v's dimensions are not positive.
+func (v Viewport) Frame() (Frame, error) {
+	if v.Width <= 0 || v.Height <= 0 {
+		return Frame{}, fmt.Errorf("image size %dx%d must be positive", v.Width, v.Height)
+	}
+	if !(v.ViewHeight > 0.0) || math.IsInf(v.ViewHeight, 0) {
+		return Frame{}, fmt.Errorf("view height %v must be positive and finite", v.ViewHeight)
+	}
+	if !(v.aspect() > 0.0) || math.IsInf(v.Aspect, 0) {
+		return Frame{}, fmt.Errorf("aspect %v must be positive and finite", v.Aspect)
+	}
+
+	dy := v.ViewHeight / float64(v.Height)
+	dx := dy * v.aspect()
+
+	flipY := 1.0
+	if v.FlipY {
+		flipY = -1.0
+	}
+
+	return Frame{
+		width:   v.Width,
+		height:  v.Height,
+		centerX: v.CenterX,
+		centerY: v.CenterY,
+		dx:      dx,
+		dy:      dy,
+		invDX:   1.0 / dx,
+		invDY:   1.0 / dy,
+		cos:     math.Cos(v.Rotation),
+		sin:     math.Sin(v.Rotation),
+		flipY:   flipY,
+	}, nil
+}
+
+// A Frame maps between plane coordinates and pixel coordinates.
+//
+// Pixel coordinates are continuous: pixel (i, j) covers [i, i+1) x [j, j+1),
+// so the fractional part of a pixel coordinate is the sub-pixel offset.
+type Frame struct {
+	width, height    int
+	centerX, centerY float64
+
+	// dx and dy are the plane sizes of a pixel.
+	dx, dy       float64
+	invDX, invDY float64
+
+	cos, sin float64
+
+	// flipY is -1.0 if plane Y increases down the image, and 1.0 otherwise.
+	flipY float64
+}
+
+// Width is the width of the image in pixels.
+func (f Frame) Width() int {
+	return f.width
+}
+
+// Height is the height of the image in pixels.
+func (f Frame) Height() int {
+	return f.height
+}
+
+// PixelWidth is the horizontal plane size of a single pixel.
+func (f Frame) PixelWidth() float64 {
+	return f.dx
+}
+
+// PixelHeight is the vertical plane size of a single pixel.
+func (f Frame) PixelHeight() float64 {
+	return f.dy
+}
+
+// ToPixel returns the continuous pixel coordinates of the plane point (x, y).
+func (f Frame) ToPixel(x, y float64) (float64, float64) {
+	x -= f.centerX
+	y = (y - f.centerY) * f.flipY
+
+	u := x*f.cos + y*f.sin
+	w := y*f.cos - x*f.sin
+
+	return u*f.invDX + 0.5*float64(f.width), 0.5*float64(f.height) - w*f.invDY
+}
+
+// FromPixel returns the plane point at the continuous pixel coordinates (px, py).
+func (f Frame) FromPixel(px, py float64) (float64, float64) {
+	u := (px - 0.5*float64(f.width)) * f.dx
+	w := (0.5*float64(f.height) - py) * f.dy
+
+	return f.centerX + u*f.cos - w*f.sin, f.centerY + (u*f.sin+w*f.cos)*f.flipY
+}
+
+// Offset returns the plane offset from the center of the image of the continuous
+// pixel coordinates (px, py), as a complex number.
+//
+// Unlike FromPixel, it keeps its precision however far the view is from the origin.
+func (f Frame) Offset(px, py float64) complex128 {
+	u := (px - 0.5*float64(f.width)) * f.dx
+	w := (0.5*float64(f.height) - py) * f.dy
+
+	return complex(u*f.cos-w*f.sin, (u*f.sin+w*f.cos)*f.flipY)
+}
+
+// ToPixelZ is ToPixel for a point on the complex plane.
+func (f Frame) ToPixelZ(z complex128) (float64, float64) {
+	return f.ToPixel(real(z), imag(z))
+}
+
+// FromPixelZ is FromPixel for a point on the complex plane.
+func (f Frame) FromPixelZ(px, py float64) complex128 {
+	x, y := f.FromPixel(px, py)
+	return complex(x, y)
+}
+
+// Index returns the index into a row-major pixel buffer of the pixel containing (x, y).
+// Returns false if the point is outside the image.
+func (f Frame) Index(x, y float64) (int, bool) {
+	px, py := f.ToPixel(x, y)
+	if px < 0.0 || py < 0.0 {
+		return -1, false
+	}
+
+	ix, iy := int(px), int(py)
+	if ix >= f.width || iy >= f.height {
+		return -1, false
+	}
+
+	return ix + iy*f.width, true
+}
@@ -0,0 +1,98 @@
+package viewport
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrame_RoundTrip(t *testing.T) {
+	tcs := []struct {
+		name string
+		v    Viewport
+	}{
+		{name: "square", v: Viewport{Width: 16, Height: 16, ViewHeight: 4}},
+		{name: "offset", v: Viewport{Width: 32, Height: 18, CenterX: -0.75, CenterY: 0.25, ViewHeight: 2.5}},
+		{name: "rotated", v: Viewport{Width: 32, Height: 18, CenterX: 1, CenterY: -2, ViewHeight: 3, Rotation: 0.7}},
+		{name: "aspect", v: Viewport{Width: 20, Height: 10, ViewHeight: 3.8, Aspect: 8.9, Rotation: -2}},
+		{name: "flipped", v: Viewport{Width: 20, Height: 10, CenterY: 2.1, ViewHeight: 3.8, Rotation: 1.2, FlipY: true}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := tc.v.Frame()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range [][2]float64{{0, 0}, {0.5, 0.5}, {3.25, 7.5}, {19.9, 9.9}, {-4, 12}} {
+				x, y := frame.FromPixel(p[0], p[1])
+				px, py := frame.ToPixel(x, y)
+				if math.Abs(px-p[0]) > 1e-9 || math.Abs(py-p[1]) > 1e-9 {
+					t.Errorf("ToPixel(FromPixel(%v, %v)) = (%v, %v)", p[0], p[1], px, py)
+				}
+
+				z := complex(tc.v.CenterX, tc.v.CenterY) + frame.Offset(p[0], p[1])
+				if math.Abs(real(z)-x) > 1e-9 || math.Abs(imag(z)-y) > 1e-9 {
+					t.Errorf("center + Offset(%v, %v) = %v, want (%v, %v)", p[0], p[1], z, x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestFrame_ToPixel(t *testing.T) {
+	tcs := []struct {
+		name   string
+		v      Viewport
+		x, y   float64
+		px, py float64
+	}{
+		{name: "center", v: Viewport{Width: 10, Height: 10, CenterX: 1, CenterY: 1, ViewHeight: 10}, x: 1, y: 1, px: 5, py: 5},
+		{name: "top edge", v: Viewport{Width: 10, Height: 10, ViewHeight: 10}, x: -5, y: 5, px: 0, py: 0},
+		{name: "up is up", v: Viewport{Width: 10, Height: 10, ViewHeight: 10}, x: 0, y: 2, px: 5, py: 3},
+		{name: "up is down", v: Viewport{Width: 10, Height: 10, ViewHeight: 10, FlipY: true}, x: 0, y: 2, px: 5, py: 7},
+		{name: "flipped top edge", v: Viewport{Width: 10, Height: 10, ViewHeight: 10, FlipY: true}, x: -5, y: -5, px: 0, py: 0},
+		// Turning the view a quarter counter-clockwise carries +X to the bottom of the image,
+		// whichever way Y points.
+		{name: "rotated", v: Viewport{Width: 10, Height: 10, ViewHeight: 10, Rotation: math.Pi / 2}, x: 2, y: 0, px: 5, py: 7},
+		{name: "rotated flipped", v: Viewport{Width: 10, Height: 10, ViewHeight: 10, Rotation: math.Pi / 2, FlipY: true}, x: 2, y: 0, px: 5, py: 7},
+		{name: "aspect", v: Viewport{Width: 10, Height: 10, ViewHeight: 10, Aspect: 2}, x: 4, y: 0, px: 7, py: 5},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := tc.v.Frame()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			px, py := frame.ToPixel(tc.x, tc.y)
+			if math.Abs(px-tc.px) > 1e-9 || math.Abs(py-tc.py) > 1e-9 {
+				t.Errorf("ToPixel(%v, %v) = (%v, %v), want (%v, %v)", tc.x, tc.y, px, py, tc.px, tc.py)
+			}
+		})
+	}
+}
+
+func TestViewport_Frame_Invalid(t *testing.T) {
+	tcs := []struct {
+		name string
+		v    Viewport
+	}{
+		{name: "zero width", v: Viewport{Width: 0, Height: 10, ViewHeight: 1}},
+		{name: "negative height", v: Viewport{Width: 10, Height: -1, ViewHeight: 1}},
+		{name: "zero view height", v: Viewport{Width: 10, Height: 10}},
+		{name: "infinite view height", v: Viewport{Width: 10, Height: 10, ViewHeight: math.Inf(1)}},
+		{name: "NaN view height", v: Viewport{Width: 10, Height: 10, ViewHeight: math.NaN()}},
+		{name: "negative aspect", v: Viewport{Width: 10, Height: 10, ViewHeight: 1, Aspect: -1}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.v.Frame()
+			if err == nil {
+				t.Errorf("%+v.Frame() = nil error, want error", tc.v)
+			}
+		})
+	}
+}